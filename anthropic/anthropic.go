@@ -0,0 +1,249 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of the Anthropic API.
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// Version is the API version sent with each request.
+const Version = "2023-06-01"
+
+// Provider is the provider name used to look up wire roles in a message.Registry.
+const Provider = "anthropic"
+
+// MetadataStopReason is the metadata key holding the reason the model stopped generating a message.
+const MetadataStopReason = "stop_reason"
+
+// ErrNoMessages is returned when a conversation has no user or assistant messages to send.
+var ErrNoMessages = errors.New("no user or assistant messages")
+
+// ErrAssistantFirst is returned when the first non-system message of a conversation is not from the user.
+var ErrAssistantFirst = errors.New("first message must be from the user")
+
+// Message is a message in the shape expected by the Messages API.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is the body of a Messages API request.
+type Request struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+}
+
+// ContentBlock is a block of content in a Messages API response.
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Usage is the number of tokens used by a Messages API request.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Response is the body of a Messages API response.
+type Response struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Model      string         `json:"model"`
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      Usage          `json:"usage"`
+}
+
+// ToMessages converts a conversation into the system prompt and messages expected by the Messages API. System messages
+// are hoisted into the system prompt, any role other than assistant is sent as the user, and consecutive messages
+// from the same role are merged into one, separated by a blank line, so that roles alternate.
+func ToMessages(c *conversation.Conversation) (system string, messages []Message, err error) {
+	return ToMessagesWithRegistry(c, nil)
+}
+
+// ToMessagesWithRegistry converts a conversation like ToMessages, after mapping each message's role to the role it
+// is sent as to the Provider with the registry's Resolve method. A nil registry leaves roles unchanged.
+func ToMessagesWithRegistry(c *conversation.Conversation, registry *message.Registry) (system string, messages []Message, err error) {
+	var systems []string
+	for _, m := range c.Messages() {
+		wire, err := registry.Resolve(message.Role(m.Role()), Provider)
+		if err != nil {
+			return "", nil, err
+		}
+		role := string(message.RoleUser)
+		switch message.Role(wire) {
+		case message.RoleSystem:
+			systems = append(systems, m.Content())
+			continue
+		case message.RoleAssistant:
+			role = string(message.RoleAssistant)
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content += "\n\n" + m.Content()
+			continue
+		}
+		messages = append(messages, Message{Role: role, Content: m.Content()})
+	}
+	if len(messages) == 0 {
+		return "", nil, ErrNoMessages
+	}
+	if messages[0].Role != string(message.RoleUser) {
+		return "", nil, ErrAssistantFirst
+	}
+	return strings.Join(systems, "\n\n"), messages, nil
+}
+
+// ToMessage converts a Messages API response into an assistant message. The text blocks of the response are joined,
+// and the model, stop reason and token usage are recorded in the message's metadata.
+func ToMessage(r Response) message.Message {
+	var content strings.Builder
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	m := message.New().
+		WithRole(message.RoleAssistant).
+		WithContent(content.String()).
+		WithMetadata(completion.MetadataModel, r.Model).
+		WithMetadata(MetadataStopReason, r.StopReason)
+	return completion.WithUsage(m, completion.Usage{PromptTokens: r.Usage.InputTokens, CompletionTokens: r.Usage.OutputTokens})
+}
+
+// Client is a completer that sends conversations to the Anthropic Messages API.
+type Client struct {
+	apiKey      string
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature *float64
+	httpClient  *http.Client
+	tokenizer   message.Tokenizer
+	registry    *message.Registry
+}
+
+// NewRequest returns the request the client sends for a conversation.
+func (c Client) NewRequest(convo *conversation.Conversation) (Request, error) {
+	system, messages, err := ToMessagesWithRegistry(convo, c.registry)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{
+		Model:       c.model,
+		System:      system,
+		Messages:    messages,
+		MaxTokens:   c.maxTokens,
+		Temperature: c.temperature,
+	}, nil
+}
+
+// Complete implements the completion.Completer interface.
+func (c Client) Complete(ctx context.Context, convo *conversation.Conversation) (message.Message, error) {
+	request, err := c.NewRequest(convo)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not convert conversation: %w", err)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.baseURL, "/")+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("Anthropic-Version", Version)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return message.Message{}, completion.NewStatusError(resp, b)
+	}
+	var response Response
+	if err := json.Unmarshal(b, &response); err != nil {
+		return message.Message{}, fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	return ToMessage(response).WithTokenizer(c.tokenizer), nil
+}
+
+// WithAPIKey configures a client with an API key.
+func (c Client) WithAPIKey(key string) Client {
+	c.apiKey = key
+	return c
+}
+
+// WithBaseURL configures a client with the base URL of the API.
+func (c Client) WithBaseURL(url string) Client {
+	c.baseURL = url
+	return c
+}
+
+// WithModel configures a client with the model to use.
+func (c Client) WithModel(model string) Client {
+	c.model = model
+	return c
+}
+
+// WithMaxTokens configures a client with the maximum number of tokens to generate.
+func (c Client) WithMaxTokens(n int) Client {
+	c.maxTokens = n
+	return c
+}
+
+// WithTemperature configures a client with the sampling temperature.
+func (c Client) WithTemperature(t float64) Client {
+	c.temperature = &t
+	return c
+}
+
+// WithHTTPClient configures a client with the HTTP client used to send requests.
+func (c Client) WithHTTPClient(client *http.Client) Client {
+	c.httpClient = client
+	return c
+}
+
+// WithTokenizer configures a client with the tokenizer given to response messages.
+func (c Client) WithTokenizer(t message.Tokenizer) Client {
+	c.tokenizer = t
+	return c
+}
+
+// WithRegistry configures a client with the registry used to map roles to the roles sent to the API. If the registry
+// is strict, conversations with unregistered roles are rejected with an error wrapping message.ErrUnregisteredRole.
+func (c Client) WithRegistry(r *message.Registry) Client {
+	c.registry = r
+	return c
+}
+
+// New creates a new client using the default base URL and HTTP client.
+func New() Client {
+	c := Client{
+		baseURL:    DefaultBaseURL,
+		maxTokens:  1024,
+		httpClient: http.DefaultClient,
+	}
+	return c
+}
@@ -0,0 +1,128 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bradfair/chat/anthropic"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToMessages(t *testing.T) {
+	t.Run("hoists system messages and merges turns", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+			message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+			message.New().WithRole(message.RoleUser).WithContent("Anyone there?"),
+			message.New().WithRole(message.RoleSystem).WithContent("Be kind."),
+			message.New().WithRole(message.RoleAssistant).WithContent("Hi!"),
+		)
+		system, messages, err := anthropic.ToMessages(c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if system != "Be brief.\n\nBe kind." {
+			t.Errorf("unexpected system prompt %q", system)
+		}
+		want := []anthropic.Message{
+			{Role: "user", Content: "Hello!\n\nAnyone there?"},
+			{Role: "assistant", Content: "Hi!"},
+		}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("expected messages to be %v, got %v", want, messages)
+		}
+	})
+	t.Run("no messages", func(t *testing.T) {
+		c := conversation.New().WithMessages(message.New().WithRole(message.RoleSystem).WithContent("Be brief."))
+		if _, _, err := anthropic.ToMessages(c); !errors.Is(err, anthropic.ErrNoMessages) {
+			t.Errorf("expected ErrNoMessages, got %v", err)
+		}
+	})
+	t.Run("registry", func(t *testing.T) {
+		registry := message.NewRegistry()
+		registry.Register("narrator", map[string]string{anthropic.Provider: "assistant"})
+		registry.SetStrict(true)
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+			message.New().WithRole("narrator").WithContent("Meanwhile..."),
+		)
+		_, messages, err := anthropic.ToMessagesWithRegistry(c, registry)
+		if err != nil || len(messages) != 2 || messages[1].Role != "assistant" {
+			t.Errorf("expected the narrator to be sent as the assistant, got %v and %v", messages, err)
+		}
+		c.Append(message.New().WithRole("critic").WithContent("Too long."))
+		if _, err := anthropic.New().WithRegistry(registry).NewRequest(c); !errors.Is(err, message.ErrUnregisteredRole) {
+			t.Errorf("expected ErrUnregisteredRole, got %v", err)
+		}
+	})
+	t.Run("assistant first", func(t *testing.T) {
+		c := conversation.New().WithMessages(message.New().WithRole(message.RoleAssistant).WithContent("Hi!"))
+		if _, _, err := anthropic.ToMessages(c); !errors.Is(err, anthropic.ErrAssistantFirst) {
+			t.Errorf("expected ErrAssistantFirst, got %v", err)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	t.Run("complete", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/messages" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			if r.Header.Get("X-Api-Key") != "key" || r.Header.Get("Anthropic-Version") != anthropic.Version {
+				t.Errorf("expected api key and version headers to be set")
+			}
+			var request anthropic.Request
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("could not decode request: %v", err)
+			}
+			if request.Model != "claude-test" || request.System != "Be brief." || request.MaxTokens != 1024 || len(request.Messages) != 1 {
+				t.Errorf("unexpected request %+v", request)
+			}
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-test","content":[{"type":"text","text":"Hi "},{"type":"text","text":"there!"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3}}`))
+		}))
+		defer server.Close()
+		client := anthropic.New().WithBaseURL(server.URL).WithAPIKey("key").WithModel("claude-test")
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+			message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+		)
+		m, err := client.Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Role() != "assistant" || m.Content() != "Hi there!" {
+			t.Errorf("unexpected message %s: %s", m.Role(), m.Content())
+		}
+		if usage := completion.UsageOf(m); usage != (completion.Usage{PromptTokens: 12, CompletionTokens: 3}) {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+		if m.Metadata()[anthropic.MetadataStopReason] != "end_turn" || m.Metadata()[completion.MetadataModel] != "claude-test" {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+	})
+	t.Run("status error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"type":"error"}`))
+		}))
+		defer server.Close()
+		c := conversation.New().WithMessages(message.New().WithRole(message.RoleUser).WithContent("Hello!"))
+		_, err := anthropic.New().WithBaseURL(server.URL).Complete(context.Background(), c)
+		var statusErr *completion.StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("expected a StatusError, got %v", err)
+		}
+		if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 2*time.Second {
+			t.Errorf("unexpected status error %+v", statusErr)
+		}
+	})
+}
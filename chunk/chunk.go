@@ -0,0 +1,282 @@
+package chunk
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/message"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MetadataChunk is the metadata key holding the zero-based index of a chunk within its document.
+const MetadataChunk = "chunk"
+
+// Chunker splits documents into chunks that fit within a token limit, breaking between paragraphs and sentences where
+// possible.
+type Chunker struct {
+	tokenizer message.Tokenizer
+	size      int
+	overlap   int
+	role      message.Role
+}
+
+// unit is a sentence, or a piece of a sentence too long to fit in a chunk, along with the number of tokens it contains.
+type unit struct {
+	text      string
+	tokens    int
+	paragraph int
+}
+
+// Split splits the text into chunks of at most the configured number of tokens. Chunks break between paragraphs where
+// a paragraph fits in a chunk of its own, and otherwise between sentences. Sentences too long for a chunk are broken
+// between words, and words too long for a chunk are broken between tokens, which requires a tokenizer that implements
+// message.Detokenizer.
+func (c Chunker) Split(text string) ([]string, error) {
+	if c.tokenizer == nil {
+		return nil, message.ErrNoTokenizer
+	}
+	if c.size <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	if c.overlap < 0 || c.overlap >= c.size {
+		return nil, errors.New("overlap must be at least zero and less than the chunk size")
+	}
+	var chunks []string
+	// current holds the units of the chunk being built, of which the last fresh units are not carried over from the
+	// previous chunk.
+	var current []unit
+	var fresh int
+	flush := func() {
+		if fresh > 0 {
+			chunks = append(chunks, join(current))
+			current, fresh = c.carry(current), 0
+		}
+	}
+	for p, paragraph := range paragraphs(text) {
+		units, err := c.units(paragraph, p)
+		if err != nil {
+			return nil, err
+		}
+		// Start a new chunk rather than split a paragraph that would fit in one.
+		if fresh > 0 {
+			whole, err := c.count(append(append([]unit{}, current...), units...))
+			if err != nil {
+				return nil, err
+			}
+			alone, err := c.count(units)
+			if err != nil {
+				return nil, err
+			}
+			if whole > c.size && alone <= c.size {
+				flush()
+			}
+		}
+		for _, u := range units {
+			fits, err := c.fits(append(append([]unit{}, current...), u))
+			if err != nil {
+				return nil, err
+			}
+			if !fits {
+				flush()
+			}
+			current = append(current, u)
+			fresh++
+			// Drop overlapping units until the new unit fits.
+			for len(current) > fresh {
+				if fits, err := c.fits(current); err != nil {
+					return nil, err
+				} else if fits {
+					break
+				}
+				current = current[1:]
+			}
+		}
+	}
+	flush()
+	return chunks, nil
+}
+
+// Chunk splits the text like Split and returns each chunk as a message with the configured role and tokenizer. Each
+// message's MetadataChunk metadata holds its index.
+func (c Chunker) Chunk(text string) ([]message.Message, error) {
+	chunks, err := c.Split(text)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]message.Message, len(chunks))
+	for i, chunk := range chunks {
+		messages[i] = message.New().
+			WithRole(c.role).
+			WithContent(chunk).
+			WithTokenizer(c.tokenizer).
+			WithMetadata(MetadataChunk, strconv.Itoa(i))
+	}
+	return messages, nil
+}
+
+// units splits a paragraph into sentences, breaking sentences and words that are too long to fit in a chunk.
+func (c Chunker) units(paragraph string, p int) ([]unit, error) {
+	var units []unit
+	for _, s := range sentences(paragraph) {
+		n, err := c.tokens(s)
+		if err != nil {
+			return nil, err
+		}
+		if n <= c.size {
+			units = append(units, unit{text: s, tokens: n, paragraph: p})
+			continue
+		}
+		var pieces []unit
+		for _, word := range strings.Fields(s) {
+			n, err := c.tokens(word)
+			if err != nil {
+				return nil, err
+			}
+			if n <= c.size {
+				pieces = append(pieces, unit{text: word, tokens: n, paragraph: p})
+				continue
+			}
+			split, err := message.New().WithContent(word).WithTokenizer(c.tokenizer).Split(c.size)
+			if err != nil {
+				return nil, fmt.Errorf("could not split word longer than chunk size: %w", err)
+			}
+			for _, m := range split {
+				n, err := c.tokens(m.Content())
+				if err != nil {
+					return nil, err
+				}
+				pieces = append(pieces, unit{text: m.Content(), tokens: n, paragraph: p})
+			}
+		}
+		units = append(units, c.pack(pieces)...)
+	}
+	return units, nil
+}
+
+// pack greedily joins the words of a long sentence into units that fit in a chunk.
+func (c Chunker) pack(words []unit) []unit {
+	var packed []unit
+	for _, w := range words {
+		if last := len(packed) - 1; last >= 0 {
+			text := packed[last].text + " " + w.text
+			if n, err := c.tokens(text); err == nil && n <= c.size {
+				packed[last].text, packed[last].tokens = text, n
+				continue
+			}
+		}
+		packed = append(packed, w)
+	}
+	return packed
+}
+
+// carry returns the trailing units of a finished chunk that fit within the configured overlap, to be repeated at the
+// start of the next chunk.
+func (c Chunker) carry(units []unit) []unit {
+	total := 0
+	i := len(units)
+	for i > 0 && total+units[i-1].tokens <= c.overlap {
+		total += units[i-1].tokens
+		i--
+	}
+	return append([]unit{}, units[i:]...)
+}
+
+// fits returns true if the units fit in a chunk once joined.
+func (c Chunker) fits(units []unit) (bool, error) {
+	n, err := c.count(units)
+	return n <= c.size, err
+}
+
+// count returns the number of tokens in the units once joined.
+func (c Chunker) count(units []unit) (int, error) {
+	return c.tokens(join(units))
+}
+
+// tokens returns the number of tokens in s.
+func (c Chunker) tokens(s string) (int, error) {
+	tokens, err := c.tokenizer.Tokenize(s)
+	if err != nil {
+		return 0, fmt.Errorf("could not tokenize text: %w", err)
+	}
+	return len(tokens), nil
+}
+
+// WithTokenizer configures a chunker with the tokenizer used to count tokens. The tokenizer is also given to the
+// messages returned by Chunk.
+func (c Chunker) WithTokenizer(t message.Tokenizer) Chunker {
+	c.tokenizer = t
+	return c
+}
+
+// WithSize configures a chunker with the maximum number of tokens in each chunk. The default is 512.
+func (c Chunker) WithSize(tokens int) Chunker {
+	c.size = tokens
+	return c
+}
+
+// WithOverlap configures a chunker to repeat up to the given number of tokens from the end of each chunk at the start
+// of the next. Overlap is made of whole sentences, so less may be repeated. The default is no overlap.
+func (c Chunker) WithOverlap(tokens int) Chunker {
+	c.overlap = tokens
+	return c
+}
+
+// WithRole configures a chunker with the role of the messages returned by Chunk. The default is the user role.
+func (c Chunker) WithRole(role message.Role) Chunker {
+	c.role = role
+	return c
+}
+
+// New creates a new chunker.
+func New() Chunker {
+	return Chunker{size: 512, role: message.RoleUser}
+}
+
+// join joins units with a space between sentences and a blank line between paragraphs.
+func join(units []unit) string {
+	var b strings.Builder
+	for i, u := range units {
+		if i > 0 {
+			if u.paragraph != units[i-1].paragraph {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(u.text)
+	}
+	return b.String()
+}
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd    = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+)
+
+// paragraphs splits text on blank lines, dropping empty paragraphs.
+func paragraphs(text string) []string {
+	var result []string
+	for _, p := range paragraphBreak.Split(strings.ReplaceAll(text, "\r\n", "\n"), -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// sentences splits a paragraph after each run of sentence-ending punctuation that is followed by whitespace.
+func sentences(paragraph string) []string {
+	var result []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+		if s := strings.TrimSpace(paragraph[start:loc[1]]); s != "" {
+			result = append(result, s)
+		}
+		start = loc[1]
+	}
+	if s := strings.TrimSpace(paragraph[start:]); s != "" {
+		result = append(result, s)
+	}
+	return result
+}
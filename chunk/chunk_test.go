@@ -0,0 +1,121 @@
+package chunk_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/chunk"
+	"github.com/bradfair/chat/message"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const document = "The cat sat. It was warm.\n\nDogs bark loudly at night. Birds sing at dawn.\r\n\r\nThe end."
+
+func TestChunker(t *testing.T) {
+	chunker := chunk.New().WithTokenizer(testTokenizer)
+	tests := []struct {
+		name    string
+		chunker chunk.Chunker
+		text    string
+		want    []string
+	}{
+		{
+			name:    "everything fits",
+			chunker: chunker.WithSize(100),
+			text:    document,
+			want:    []string{"The cat sat. It was warm.\n\nDogs bark loudly at night. Birds sing at dawn.\n\nThe end."},
+		},
+		{
+			name:    "paragraphs",
+			chunker: chunker.WithSize(11),
+			text:    document,
+			want:    []string{"The cat sat. It was warm.", "Dogs bark loudly at night. Birds sing at dawn.\n\nThe end."},
+		},
+		{
+			name:    "sentences",
+			chunker: chunker.WithSize(6),
+			text:    document,
+			want:    []string{"The cat sat. It was warm.", "Dogs bark loudly at night.", "Birds sing at dawn.\n\nThe end."},
+		},
+		{
+			name:    "overlap",
+			chunker: chunker.WithSize(8).WithOverlap(4),
+			text:    "One two. Three four five. Six seven. Eight nine ten.",
+			want:    []string{"One two. Three four five. Six seven.", "Six seven. Eight nine ten."},
+		},
+		{
+			name:    "long sentence",
+			chunker: chunker.WithSize(3),
+			text:    "a b c d e f g.",
+			want:    []string{"a b c", "d e f", "g."},
+		},
+		{
+			name:    "empty",
+			chunker: chunker,
+			text:    " \n\n ",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.chunker.Split(tt.text)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected chunks %q, got %q", tt.want, got)
+			}
+		})
+	}
+	t.Run("long word", func(t *testing.T) {
+		codec := message.Codec{
+			Tokenizer: message.TokenizerFunc(func(s string) ([]int, error) { return make([]int, len(s)), nil }),
+			Detokenizer: message.DetokenizerFunc(func(tokens []int) (string, error) {
+				return strings.Repeat("x", len(tokens)), nil
+			}),
+		}
+		got, err := chunk.New().WithTokenizer(codec).WithSize(4).Split("xxxxxxxxxx")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(got, []string{"xxxx", "xxxx", "xx"}) {
+			t.Errorf("unexpected chunks %q", got)
+		}
+		if _, err := chunk.New().WithTokenizer(codec.Tokenizer).WithSize(1).Split("xx"); !errors.Is(err, message.ErrNoDetokenizer) {
+			t.Errorf("expected ErrNoDetokenizer, got %v", err)
+		}
+	})
+	t.Run("messages", func(t *testing.T) {
+		messages, err := chunker.WithSize(11).WithRole(message.RoleSystem).Chunk(document)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("expected 2 messages, got %d", len(messages))
+		}
+		for i, m := range messages {
+			if m.Role() != "system" || m.Metadata()[chunk.MetadataChunk] != []string{"0", "1"}[i] {
+				t.Errorf("unexpected message %d: %s %v", i, m.Role(), m.Metadata())
+			}
+			if tokens, err := m.Tokenize(); err != nil || len(tokens) > 11 {
+				t.Errorf("expected message %d to fit in 11 tokens, got %d (%v)", i, len(tokens), err)
+			}
+		}
+	})
+	t.Run("invalid configuration", func(t *testing.T) {
+		if _, err := chunk.New().Split(document); !errors.Is(err, message.ErrNoTokenizer) {
+			t.Errorf("expected ErrNoTokenizer, got %v", err)
+		}
+		if _, err := chunker.WithSize(0).Split(document); err == nil {
+			t.Errorf("expected an error for a chunk size of 0")
+		}
+		if _, err := chunker.WithSize(4).WithOverlap(4).Split(document); err == nil {
+			t.Errorf("expected an error for an overlap as large as the chunk size")
+		}
+	})
+}
+
+// testTokenizer treats each space-separated word as a token.
+var testTokenizer = message.TokenizerFunc(func(s string) ([]int, error) {
+	return make([]int, len(strings.Fields(s))), nil
+})
@@ -0,0 +1,139 @@
+package compaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+// MetadataSummary is the metadata key used to mark a message as a summary of earlier messages.
+const MetadataSummary = "summary"
+
+// DefaultPrompt is the prompt used to request a summary when no other prompt is configured.
+const DefaultPrompt = "Without responding to any previous message, please briefly summarize the conversation so far."
+
+// DefaultThreshold is the number of tokens a conversation may contain before it is compacted, when no other threshold
+// is configured.
+const DefaultThreshold = 3000
+
+// DefaultKeepRecent is the number of most recent messages that are never summarized, when no other number is
+// configured.
+const DefaultKeepRecent = 6
+
+// ErrNoCompleter is returned when a compactor has no completer.
+var ErrNoCompleter = errors.New("no completer")
+
+// Compactor replaces the oldest messages of a conversation with a summary once the conversation grows past a token threshold.
+type Compactor struct {
+	completer completion.Completer
+	tokenizer message.Tokenizer
+	prompt    string
+	threshold int
+	keep      int
+	recursive bool
+}
+
+// Compact returns a compacted child of the given conversation. The oldest messages are summarized by the completer and
+// replaced with a single summary message, while the most recent messages are kept verbatim. If the conversation does
+// not exceed the threshold, or there is nothing to summarize, the original conversation is returned unchanged.
+func (c Compactor) Compact(ctx context.Context, convo *conversation.Conversation) (*conversation.Conversation, error) {
+	if c.completer == nil {
+		return nil, ErrNoCompleter
+	}
+	count, err := convo.CountTokens()
+	if err != nil {
+		return nil, fmt.Errorf("could not count tokens: %w", err)
+	}
+	if count <= c.threshold {
+		return convo, nil
+	}
+
+	messages := convo.Messages()
+	split := len(messages) - c.keep
+	if split < 0 {
+		split = 0
+	}
+	var summaries, span conversation.Messages
+	for _, m := range messages[:split] {
+		if IsSummary(m) && !c.recursive {
+			summaries = append(summaries, m)
+			continue
+		}
+		span = append(span, m)
+	}
+	if len(span) == 0 || (len(span) == 1 && IsSummary(span[0])) {
+		return convo, nil
+	}
+
+	request := convo.NewChild().WithMessages(span...)
+	request.Append(message.New().WithRole(message.RoleSystem).WithContent(c.prompt))
+	response, err := c.completer.Complete(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("could not summarize conversation: %w", err)
+	}
+	summary := response.WithRole(message.RoleSystem).WithMetadata(MetadataSummary, "true")
+	if c.tokenizer != nil {
+		summary = summary.WithTokenizer(c.tokenizer)
+	}
+
+	compacted := make([]conversation.Message, 0, len(summaries)+1+len(messages)-split)
+	compacted = append(compacted, summaries...)
+	compacted = append(compacted, summary)
+	compacted = append(compacted, messages[split:]...)
+	return convo.NewChild().WithMessages(compacted...), nil
+}
+
+// WithCompleter configures a compactor with the completer used to generate summaries.
+func (c Compactor) WithCompleter(completer completion.Completer) Compactor {
+	c.completer = completer
+	return c
+}
+
+// WithTokenizer configures a compactor with the tokenizer given to summary messages.
+// If no tokenizer is configured, summary messages keep the tokenizer set by the completer, if any.
+func (c Compactor) WithTokenizer(t message.Tokenizer) Compactor {
+	c.tokenizer = t
+	return c
+}
+
+// WithPrompt configures a compactor with the prompt used to request a summary.
+func (c Compactor) WithPrompt(prompt string) Compactor {
+	c.prompt = prompt
+	return c
+}
+
+// WithThreshold configures a compactor with the number of tokens a conversation may contain before it is compacted.
+// The default is DefaultThreshold.
+func (c Compactor) WithThreshold(tokens int) Compactor {
+	c.threshold = tokens
+	return c
+}
+
+// WithKeepRecent configures a compactor with the number of most recent messages that are never summarized.
+// The default is DefaultKeepRecent.
+func (c Compactor) WithKeepRecent(n int) Compactor {
+	c.keep = n
+	return c
+}
+
+// WithRecursive configures whether earlier summaries are summarized again along with the messages that follow them.
+// When disabled, earlier summaries are kept verbatim ahead of the new summary.
+func (c Compactor) WithRecursive(recursive bool) Compactor {
+	c.recursive = recursive
+	return c
+}
+
+// IsSummary returns true if the message was marked as a summary by a compactor.
+func IsSummary(m conversation.Message) bool {
+	md, ok := m.(interface{ Metadata() map[string]string })
+	return ok && md.Metadata()[MetadataSummary] == "true"
+}
+
+// New creates a new compactor using DefaultPrompt, DefaultThreshold and DefaultKeepRecent.
+func New() Compactor {
+	c := Compactor{prompt: DefaultPrompt, threshold: DefaultThreshold, keep: DefaultKeepRecent}
+	return c
+}
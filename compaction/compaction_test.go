@@ -0,0 +1,156 @@
+package compaction_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/compaction"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+	"testing"
+)
+
+func TestCompactor(t *testing.T) {
+	t.Run("no completer", func(t *testing.T) {
+		_, err := compaction.New().Compact(context.Background(), testConversation(4))
+		if !errors.Is(err, compaction.ErrNoCompleter) {
+			t.Errorf("expected ErrNoCompleter, got %v", err)
+		}
+	})
+	t.Run("below threshold", func(t *testing.T) {
+		c := testConversation(4)
+		compacted, err := compaction.New().WithCompleter(testCompleter(nil)).WithThreshold(100).Compact(context.Background(), c)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if compacted != c {
+			t.Errorf("expected conversation to be returned unchanged")
+		}
+	})
+	t.Run("defaults", func(t *testing.T) {
+		c := testConversation(20)
+		compacted, err := compaction.New().WithCompleter(testCompleter(nil)).Compact(context.Background(), c)
+		if err != nil || compacted != c {
+			t.Errorf("expected a short conversation to be returned unchanged, got %v", err)
+		}
+		compacted, err = compaction.New().WithCompleter(testCompleter(nil)).WithThreshold(5).Compact(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if compacted.Messages().Len() != 1+compaction.DefaultKeepRecent {
+			t.Errorf("expected the %d most recent messages to be kept, got %d messages", compaction.DefaultKeepRecent, compacted.Messages().Len())
+		}
+	})
+	t.Run("summarizes oldest messages", func(t *testing.T) {
+		var requests []*conversation.Conversation
+		c := testConversation(6)
+		compacted, err := compaction.New().
+			WithCompleter(testCompleter(&requests)).
+			WithThreshold(5).
+			WithKeepRecent(2).
+			Compact(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if compacted.Parent() != c {
+			t.Errorf("expected compacted conversation to be a child of the original")
+		}
+		if compacted.Messages().Len() != 3 {
+			t.Fatalf("expected 3 messages, got %d", compacted.Messages().Len())
+		}
+		if !compaction.IsSummary(compacted.Message(0)) {
+			t.Errorf("expected first message to be a summary")
+		}
+		if compacted.Message(0).Content() != "summary of 4 messages" {
+			t.Errorf("unexpected summary content %q", compacted.Message(0).Content())
+		}
+		if compacted.Message(1).Content() != "message 5" || compacted.Message(2).Content() != "message 6" {
+			t.Errorf("expected recent messages to be kept verbatim")
+		}
+		if c.Messages().Len() != 6 || len(c.Children()) != 0 {
+			t.Errorf("expected original conversation to be unchanged")
+		}
+		if len(requests) != 1 || requests[0].Message(4).Content() != compaction.DefaultPrompt {
+			t.Errorf("expected summary prompt to be sent after the summarized span")
+		}
+	})
+	t.Run("keeps prior summaries", func(t *testing.T) {
+		compactor := compaction.New().WithCompleter(testCompleter(nil)).WithThreshold(5).WithKeepRecent(2)
+		c, err := compactor.Compact(context.Background(), testConversation(6))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		c.Append(testMessage("message 7"))
+		c.Append(testMessage("message 8"))
+		c, err = compactor.Compact(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if c.Messages().Len() != 4 {
+			t.Fatalf("expected 4 messages, got %d", c.Messages().Len())
+		}
+		if c.Message(0).Content() != "summary of 4 messages" || c.Message(1).Content() != "summary of 2 messages" {
+			t.Errorf("expected prior summary to be kept ahead of the new summary, got %q", c.Messages().Transcript())
+		}
+	})
+	t.Run("recursive", func(t *testing.T) {
+		compactor := compaction.New().WithCompleter(testCompleter(nil)).WithThreshold(5).WithKeepRecent(2).WithRecursive(true)
+		c, err := compactor.Compact(context.Background(), testConversation(6))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		c.Append(testMessage("message 7"))
+		c.Append(testMessage("message 8"))
+		c, err = compactor.Compact(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if c.Messages().Len() != 3 {
+			t.Fatalf("expected 3 messages, got %d", c.Messages().Len())
+		}
+		if c.Message(0).Content() != "summary of 3 messages" {
+			t.Errorf("expected prior summary to be summarized again, got %q", c.Message(0).Content())
+		}
+	})
+	t.Run("completer error", func(t *testing.T) {
+		errCompleting := errors.New("error completing")
+		failing := completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+			return message.Message{}, errCompleting
+		})
+		_, err := compaction.New().WithCompleter(failing).WithThreshold(5).WithKeepRecent(2).Compact(context.Background(), testConversation(4))
+		if !errors.Is(err, errCompleting) {
+			t.Errorf("expected %v, got %v", errCompleting, err)
+		}
+	})
+}
+
+func testConversation(n int) *conversation.Conversation {
+	c := conversation.New()
+	for i := 1; i <= n; i++ {
+		c.Append(testMessage("message " + string(rune('0'+i))))
+	}
+	return c
+}
+
+func testMessage(content string) message.Message {
+	return message.New().WithRole(message.RoleUser).WithContent(content).WithTokenizer(message.TokenizerFunc(testTokenizer))
+}
+
+// testCompleter summarizes a conversation by counting the messages that precede the summary prompt.
+func testCompleter(requests *[]*conversation.Conversation) completion.Completer {
+	return completion.CompleterFunc(func(_ context.Context, c *conversation.Conversation) (message.Message, error) {
+		if requests != nil {
+			*requests = append(*requests, c)
+		}
+		content := "summary of " + string(rune('0'+c.Messages().Len()-1)) + " messages"
+		return message.New().WithRole(message.RoleAssistant).WithContent(content).WithTokenizer(message.TokenizerFunc(testTokenizer)), nil
+	})
+}
+
+func testTokenizer(content string) (tokens []int, err error) {
+	for id := range strings.Split(content, " ") {
+		tokens = append(tokens, id)
+	}
+	return
+}
@@ -0,0 +1,184 @@
+package completion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataCache is the metadata key set to "hit" on messages returned from a cache.
+const MetadataCache = "cache"
+
+// billing lists the metadata keys describing what a completion cost, which don't apply to cached replies.
+var billing = []string{MetadataPromptTokens, MetadataCompletionTokens, MetadataCost, MetadataUsageEstimated, MetadataCompletionID}
+
+// CacheEntry is a cached message.
+type CacheEntry struct {
+	Role     string            `json:"role"`
+	Content  string            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Expires is the time after which the entry must not be used. The zero time means the entry never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// CacheStore stores cache entries by key.
+type CacheStore interface {
+	// Get returns the entry for the key, and false if there is none.
+	Get(key string) (CacheEntry, bool, error)
+	// Set stores the entry for the key.
+	Set(key string, entry CacheEntry) error
+}
+
+type bypassKey struct{}
+
+// WithoutCache returns a context that makes caching completers skip the cache for requests made with it.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Cache returns stored replies for conversations it has seen before, instead of calling the completer again.
+type Cache struct {
+	store            CacheStore
+	ttl              time.Duration
+	params           map[string]string
+	nonDeterministic bool
+	tokenizer        message.Tokenizer
+	onWriteError     func(error)
+}
+
+// Wrap returns a completer that caches the replies of the given completer. It can be used as Middleware.
+//
+// Replies are keyed by the roles and contents of the conversation's messages, with surrounding whitespace trimmed and
+// line endings normalized, and by the cache's model parameters. Cached replies are returned with MetadataCache set to
+// "hit", and without the usage and cost recorded on the original reply, since serving them cost nothing. The cache is
+// skipped for requests made with a context from WithoutCache, and, unless configured otherwise,
+// when the "temperature" parameter is above zero, since such replies are not deterministic.
+func (c Cache) Wrap(next Completer) Completer {
+	return CompleterFunc(func(ctx context.Context, convo *conversation.Conversation) (message.Message, error) {
+		if c.bypassed(ctx) {
+			return next.Complete(ctx, convo)
+		}
+		key, err := c.Key(convo)
+		if err != nil {
+			return message.Message{}, err
+		}
+		entry, ok, err := c.store.Get(key)
+		if err != nil {
+			return message.Message{}, fmt.Errorf("could not read cache: %w", err)
+		}
+		if ok && (entry.Expires.IsZero() || time.Now().Before(entry.Expires)) {
+			m := message.New().WithRole(message.Role(entry.Role)).WithContent(entry.Content).WithTokenizer(c.tokenizer)
+			for k, v := range entry.Metadata {
+				m = m.WithMetadata(k, v)
+			}
+			return m.WithoutMetadata(billing...).WithMetadata(MetadataCache, "hit"), nil
+		}
+		m, err := next.Complete(ctx, convo)
+		if err != nil {
+			return m, err
+		}
+		entry = CacheEntry{Role: m.Role(), Content: m.Content(), Metadata: m.WithoutMetadata(billing...).Metadata()}
+		if c.ttl > 0 {
+			entry.Expires = time.Now().Add(c.ttl)
+		}
+		if err := c.store.Set(key, entry); err != nil && c.onWriteError != nil {
+			c.onWriteError(fmt.Errorf("could not write cache: %w", err))
+		}
+		return m, nil
+	})
+}
+
+// Key returns the cache key for a conversation.
+func (c Cache) Key(convo *conversation.Conversation) (string, error) {
+	type normalized struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	var messages []normalized
+	for _, m := range convo.Messages() {
+		content := strings.TrimSpace(strings.ReplaceAll(m.Content(), "\r\n", "\n"))
+		messages = append(messages, normalized{Role: m.Role(), Content: content})
+	}
+	keys := make([]string, 0, len(c.params))
+	for k := range c.params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, [2]string{k, c.params[k]})
+	}
+	b, err := json.Marshal(struct {
+		Messages []normalized `json:"messages"`
+		Params   [][2]string  `json:"params"`
+	}{messages, params})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal cache key: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// bypassed returns true if a request should skip the cache.
+func (c Cache) bypassed(ctx context.Context) bool {
+	if bypass, _ := ctx.Value(bypassKey{}).(bool); bypass {
+		return true
+	}
+	if c.nonDeterministic {
+		return false
+	}
+	temperature, err := strconv.ParseFloat(c.params["temperature"], 64)
+	return err == nil && temperature > 0
+}
+
+// WithTTL configures a cache with how long entries are used for. Zero means entries never expire.
+func (c Cache) WithTTL(ttl time.Duration) Cache {
+	c.ttl = ttl
+	return c
+}
+
+// WithParam configures a cache with a model parameter, such as the model name or temperature, that is included in the
+// cache key. Completers with different parameters should be given different parameters here, so they don't share
+// replies.
+func (c Cache) WithParam(key string, value any) Cache {
+	params := make(map[string]string, len(c.params)+1)
+	for k, v := range c.params {
+		params[k] = v
+	}
+	params[key] = fmt.Sprint(value)
+	c.params = params
+	return c
+}
+
+// WithNonDeterministic configures whether the cache is used when the "temperature" parameter is above zero.
+func (c Cache) WithNonDeterministic(cache bool) Cache {
+	c.nonDeterministic = cache
+	return c
+}
+
+// WithTokenizer configures a cache with the tokenizer given to cached messages.
+func (c Cache) WithTokenizer(t message.Tokenizer) Cache {
+	c.tokenizer = t
+	return c
+}
+
+// WithWriteErrorHandler configures a cache with a function called when a reply can't be stored. The reply is returned
+// to the caller either way, so a failing store doesn't fail requests; without a handler, write errors are ignored.
+func (c Cache) WithWriteErrorHandler(fn func(error)) Cache {
+	c.onWriteError = fn
+	return c
+}
+
+// NewCache creates a new cache backed by the store.
+func NewCache(store CacheStore) Cache {
+	c := Cache{store: store}
+	return c
+}
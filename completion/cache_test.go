@@ -0,0 +1,168 @@
+package completion_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	counting := func(calls *int) completion.Completer {
+		return testCompleter(func() error {
+			*calls++
+			return nil
+		})
+	}
+	stores := map[string]func(t *testing.T) completion.CacheStore{
+		"memory": func(t *testing.T) completion.CacheStore { return completion.NewMemoryStore(10) },
+		"disk":   func(t *testing.T) completion.CacheStore { return completion.NewDiskStore(t.TempDir()) },
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			var calls int
+			cached := completion.NewCache(store(t)).Wrap(counting(&calls))
+			first, err := cached.Complete(context.Background(), testConversation())
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			second, err := cached.Complete(context.Background(), testConversation())
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if calls != 1 {
+				t.Errorf("expected completer to be called once, got %d", calls)
+			}
+			if second.Content() != first.Content() || second.Role() != first.Role() {
+				t.Errorf("expected cached reply to match, got %s: %s", second.Role(), second.Content())
+			}
+			if first.Metadata()[completion.MetadataCache] != "" || second.Metadata()[completion.MetadataCache] != "hit" {
+				t.Errorf("expected only the second reply to be a cache hit")
+			}
+		})
+	}
+	t.Run("normalizes whitespace", func(t *testing.T) {
+		cache := completion.NewCache(completion.NewMemoryStore(0))
+		a, _ := cache.Key(conversation.New().WithMessages(message.New().WithRole("user").WithContent("hello\r\nthere ")))
+		b, _ := cache.Key(conversation.New().WithMessages(message.New().WithRole("user").WithContent("hello\nthere")))
+		if a != b {
+			t.Errorf("expected normalized conversations to share a key")
+		}
+		c, _ := cache.WithParam("model", "other").Key(conversation.New().WithMessages(message.New().WithRole("user").WithContent("hello\nthere")))
+		if a == c {
+			t.Errorf("expected params to change the key")
+		}
+	})
+	t.Run("ttl", func(t *testing.T) {
+		var calls int
+		cached := completion.NewCache(completion.NewMemoryStore(0)).WithTTL(time.Nanosecond).Wrap(counting(&calls))
+		_, _ = cached.Complete(context.Background(), testConversation())
+		time.Sleep(time.Millisecond)
+		_, _ = cached.Complete(context.Background(), testConversation())
+		if calls != 2 {
+			t.Errorf("expected expired entry to be replaced, got %d calls", calls)
+		}
+	})
+	t.Run("bypass", func(t *testing.T) {
+		var calls int
+		cache := completion.NewCache(completion.NewMemoryStore(0))
+		cached := cache.WithParam("temperature", 0.7).Wrap(counting(&calls))
+		_, _ = cached.Complete(context.Background(), testConversation())
+		_, _ = cached.Complete(context.Background(), testConversation())
+		if calls != 2 {
+			t.Errorf("expected non-deterministic requests to bypass the cache, got %d calls", calls)
+		}
+		calls = 0
+		cached = cache.WithParam("temperature", 0).Wrap(counting(&calls))
+		_, _ = cached.Complete(completion.WithoutCache(context.Background()), testConversation())
+		_, _ = cached.Complete(completion.WithoutCache(context.Background()), testConversation())
+		if calls != 2 {
+			t.Errorf("expected WithoutCache to bypass the cache, got %d calls", calls)
+		}
+		calls = 0
+		cached = cache.WithParam("temperature", 0.7).WithNonDeterministic(true).Wrap(counting(&calls))
+		_, _ = cached.Complete(context.Background(), testConversation())
+		_, _ = cached.Complete(context.Background(), testConversation())
+		if calls != 1 {
+			t.Errorf("expected WithNonDeterministic to use the cache, got %d calls", calls)
+		}
+	})
+	t.Run("write errors", func(t *testing.T) {
+		var calls int
+		var reported error
+		cached := completion.NewCache(failingStore{}).WithWriteErrorHandler(func(err error) { reported = err }).Wrap(counting(&calls))
+		m, err := cached.Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if calls != 1 || m.Content() == "" {
+			t.Errorf("expected the completer's reply to be returned, got %q after %d calls", m.Content(), calls)
+		}
+		if !errors.Is(reported, errWrite) {
+			t.Errorf("expected the write error to be reported, got %v", reported)
+		}
+	})
+	t.Run("hits are not billed", func(t *testing.T) {
+		var calls int
+		reply := completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+			calls++
+			m := message.New().WithRole("assistant").WithContent("reply").WithMetadata(completion.MetadataModel, "small")
+			return completion.WithUsage(m, completion.Usage{PromptTokens: 1000, CompletionTokens: 500}), nil
+		})
+		meter := completion.NewMeter().WithDefaultPrice(completion.Price{PromptPer1K: 1, CompletionPer1K: 2})
+		chains := map[string][]completion.Middleware{
+			"meter outside": {meter.Wrap, completion.NewCache(completion.NewMemoryStore(10)).Wrap},
+			"meter inside":  {completion.NewCache(completion.NewMemoryStore(10)).Wrap, meter.Wrap},
+		}
+		for name, middleware := range chains {
+			calls = 0
+			completer := completion.Chain(reply, middleware...)
+			first, _ := completer.Complete(context.Background(), testConversation())
+			second, err := completer.Complete(context.Background(), testConversation())
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", name, err)
+			}
+			if calls != 1 || completion.CostOf(first) != 2 {
+				t.Errorf("%s: expected one billed call, got %d calls costing %v", name, calls, completion.CostOf(first))
+			}
+			if completion.CostOf(second) != 0 || completion.UsageOf(second) != (completion.Usage{}) {
+				t.Errorf("%s: expected the cache hit to have no usage or cost, got %v", name, second.Metadata())
+			}
+			if second.Metadata()[completion.MetadataModel] != "small" {
+				t.Errorf("%s: expected other metadata to be kept, got %v", name, second.Metadata())
+			}
+		}
+	})
+	t.Run("memory store evicts least recently used", func(t *testing.T) {
+		store := completion.NewMemoryStore(2)
+		_ = store.Set("a", completion.CacheEntry{Content: "a"})
+		_ = store.Set("b", completion.CacheEntry{Content: "b"})
+		_, _, _ = store.Get("a")
+		_ = store.Set("c", completion.CacheEntry{Content: "c"})
+		if _, ok, _ := store.Get("b"); ok {
+			t.Errorf("expected b to be evicted")
+		}
+		if _, ok, _ := store.Get("a"); !ok {
+			t.Errorf("expected a to be kept")
+		}
+		if store.Len() != 2 {
+			t.Errorf("expected 2 entries, got %d", store.Len())
+		}
+	})
+}
+
+var errWrite = errors.New("write failed")
+
+// failingStore is a cache store that holds nothing and fails every write.
+type failingStore struct{}
+
+func (failingStore) Get(string) (completion.CacheEntry, bool, error) {
+	return completion.CacheEntry{}, false, nil
+}
+
+func (failingStore) Set(string, completion.CacheEntry) error {
+	return errWrite
+}
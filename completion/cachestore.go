@@ -0,0 +1,129 @@
+package completion
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore is an in-memory cache store that evicts the least recently used entry once it is full. It is safe for
+// concurrent use.
+type MemoryStore struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+type memoryEntry struct {
+	key   string
+	entry CacheEntry
+}
+
+// Get implements the CacheStore interface.
+func (s *MemoryStore) Get(key string) (CacheEntry, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return CacheEntry{}, false, nil
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(memoryEntry).entry, true, nil
+}
+
+// Set implements the CacheStore interface.
+func (s *MemoryStore) Set(key string, entry CacheEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.Value = memoryEntry{key: key, entry: entry}
+		s.order.MoveToFront(e)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(memoryEntry{key: key, entry: entry})
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the store.
+func (s *MemoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.order.Len()
+}
+
+// NewMemoryStore creates a new in-memory store holding up to capacity entries. A capacity of zero or less is unlimited.
+func NewMemoryStore(capacity int) *MemoryStore {
+	s := &MemoryStore{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+	return s
+}
+
+// DiskStore is a cache store that keeps each entry in a JSON file in a directory.
+type DiskStore struct {
+	dir string
+}
+
+// Get implements the CacheStore interface.
+func (s DiskStore) Get(key string) (CacheEntry, bool, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return CacheEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return CacheEntry{}, false, fmt.Errorf("could not unmarshal cache entry: %w", err)
+	}
+	return entry, true, nil
+}
+
+// Set implements the CacheStore interface. Entries are written to a temporary file and renamed into place, so readers
+// never see a partially written entry.
+func (s DiskStore) Set(key string, entry CacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal cache entry: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s DiskStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// NewDiskStore creates a new store that keeps entries in the directory, which is created if it doesn't exist.
+func NewDiskStore(dir string) DiskStore {
+	s := DiskStore{dir: dir}
+	return s
+}
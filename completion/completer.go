@@ -0,0 +1,32 @@
+package completion
+
+import (
+	"context"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+// MetadataModel is the metadata key holding the name of the model that produced a message.
+const MetadataModel = "model"
+
+// Completer produces the next message in a conversation, typically by sending it to a chat completion API.
+type Completer interface {
+	// Complete returns the message that follows the given conversation.
+	Complete(ctx context.Context, c *conversation.Conversation) (message.Message, error)
+}
+
+// CompleterFunc wraps a function as a completer.
+type CompleterFunc func(ctx context.Context, c *conversation.Conversation) (message.Message, error)
+
+// Complete calls the wrapped function.
+func (f CompleterFunc) Complete(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+	return f(ctx, c)
+}
+
+// Streamer is a completer that can deliver the next message in a conversation incrementally as it is generated.
+type Streamer interface {
+	Completer
+	// Stream calls fn with each chunk of content as it is received, and returns the complete message once generation
+	// has finished. If fn returns an error, streaming stops and the error is returned.
+	Stream(ctx context.Context, c *conversation.Conversation, fn func(chunk string) error) (message.Message, error)
+}
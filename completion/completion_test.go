@@ -0,0 +1,87 @@
+package completion_test
+
+import (
+	"context"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	m := completion.WithUsage(message.New(), completion.Usage{PromptTokens: 10, CompletionTokens: 5})
+	usage := completion.UsageOf(m)
+	if usage.PromptTokens != 10 || usage.CompletionTokens != 5 || usage.TotalTokens() != 15 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if completion.UsageOf(message.New()) != (completion.Usage{}) {
+		t.Errorf("expected zero usage for a message without usage metadata")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	t.Run("retry after seconds", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+		err := completion.NewStatusError(resp, []byte("slow down"))
+		if err.RetryAfter != 3*time.Second {
+			t.Errorf("expected retry after to be 3s, got %v", err.RetryAfter)
+		}
+		if err.Error() != "unexpected status 429 Too Many Requests: slow down" {
+			t.Errorf("unexpected error message %q", err.Error())
+		}
+	})
+	t.Run("retry after date", func(t *testing.T) {
+		date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+		resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {date}}}
+		err := completion.NewStatusError(resp, nil)
+		if err.RetryAfter <= 0 || err.RetryAfter > time.Minute {
+			t.Errorf("expected retry after to be within a minute, got %v", err.RetryAfter)
+		}
+	})
+}
+
+func TestMeter(t *testing.T) {
+	t.Run("prices reported usage by model", func(t *testing.T) {
+		reply := completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+			m := message.New().WithRole("assistant").WithContent("reply").WithMetadata(completion.MetadataModel, "small")
+			return completion.WithUsage(m, completion.Usage{PromptTokens: 1000, CompletionTokens: 500}), nil
+		})
+		meter := completion.NewMeter().
+			WithPrice("small", completion.Price{PromptPer1K: 1, CompletionPer1K: 2}).
+			WithDefaultPrice(completion.Price{PromptPer1K: 10, CompletionPer1K: 20})
+		m, err := meter.Wrap(reply).Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if completion.CostOf(m) != 2 {
+			t.Errorf("expected cost to be 2, got %v", completion.CostOf(m))
+		}
+	})
+	t.Run("estimates missing usage", func(t *testing.T) {
+		reply := completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+			return message.New().WithRole("assistant").WithContent("four five").WithTokenizer(message.TokenizerFunc(testTokenizer)), nil
+		})
+		meter := completion.NewMeter().WithDefaultPrice(completion.Price{PromptPer1K: 1000, CompletionPer1K: 1000})
+		m, err := meter.Wrap(reply).Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if u := completion.UsageOf(m); u.PromptTokens != 4 || u.CompletionTokens != 2 {
+			t.Errorf("unexpected usage %+v", u)
+		}
+		if m.Metadata()[completion.MetadataUsageEstimated] != "true" || completion.CostOf(m) != 6 {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+	})
+	t.Run("leaves replies without usage unpriced", func(t *testing.T) {
+		m, err := completion.NewMeter().Wrap(testCompleter(nil)).Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, ok := m.Metadata()[completion.MetadataCost]; ok {
+			t.Errorf("expected no cost to be recorded, got %v", m.Metadata())
+		}
+	})
+}
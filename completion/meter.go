@@ -0,0 +1,96 @@
+package completion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+// Price is the dollar price of a model's tokens.
+type Price struct {
+	// PromptPer1K is the price of 1,000 prompt tokens.
+	PromptPer1K float64
+	// CompletionPer1K is the price of 1,000 completion tokens.
+	CompletionPer1K float64
+}
+
+// Cost returns the dollar cost of the usage.
+func (p Price) Cost(u Usage) float64 {
+	return float64(u.PromptTokens)/1000*p.PromptPer1K + float64(u.CompletionTokens)/1000*p.CompletionPer1K
+}
+
+// Meter records the usage and cost of completions on the messages they produce.
+type Meter struct {
+	prices       map[string]Price
+	defaultPrice Price
+}
+
+// Wrap returns a completer that records usage and cost on the replies of the given completer. It can be used as
+// Middleware.
+//
+// If the completer didn't report usage, it is counted with the conversation's CountTokens method and the reply's
+// Tokenize method, and MetadataUsageEstimated is set. If neither can be counted, the reply is returned without usage.
+// The cost is calculated with the price of the model recorded in the reply's MetadataModel, or the default price if
+// the model has no price. Each metered reply is given a unique MetadataCompletionID.
+//
+// Replies served from a Cache (with MetadataCache set to "hit") didn't cost anything, so they are returned unmetered.
+func (mt Meter) Wrap(next Completer) Completer {
+	return CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		m, err := next.Complete(ctx, c)
+		if err != nil || m.Metadata()[MetadataCache] == "hit" {
+			return m, err
+		}
+		if id := completionID(); id != "" {
+			m = m.WithMetadata(MetadataCompletionID, id)
+		}
+		usage := UsageOf(m)
+		if usage == (Usage{}) {
+			prompt, promptErr := c.CountTokens()
+			completion, completionErr := m.Tokenize()
+			if promptErr != nil || completionErr != nil {
+				return m, nil
+			}
+			usage = Usage{PromptTokens: prompt, CompletionTokens: len(completion)}
+			m = WithUsage(m, usage).WithMetadata(MetadataUsageEstimated, "true")
+		}
+		price, ok := mt.prices[m.Metadata()[MetadataModel]]
+		if !ok {
+			price = mt.defaultPrice
+		}
+		return WithCost(m, price.Cost(usage)), nil
+	})
+}
+
+// completionID returns a random ID for a completion.
+func completionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithPrice configures a meter with the price of a model.
+func (mt Meter) WithPrice(model string, p Price) Meter {
+	prices := make(map[string]Price, len(mt.prices)+1)
+	for k, v := range mt.prices {
+		prices[k] = v
+	}
+	prices[model] = p
+	mt.prices = prices
+	return mt
+}
+
+// WithDefaultPrice configures a meter with the price used for models without a price.
+func (mt Meter) WithDefaultPrice(p Price) Meter {
+	mt.defaultPrice = p
+	return mt
+}
+
+// NewMeter creates a new meter with no prices.
+func NewMeter() Meter {
+	mt := Meter{}
+	return mt
+}
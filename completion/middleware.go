@@ -0,0 +1,12 @@
+package completion
+
+// Middleware wraps a completer with additional behavior, such as retries or rate limiting.
+type Middleware func(Completer) Completer
+
+// Chain wraps a completer with middleware. The first middleware is the outermost, so it sees each request first.
+func Chain(c Completer, middleware ...Middleware) Completer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		c = middleware[i](c)
+	}
+	return c
+}
@@ -0,0 +1,148 @@
+package completion_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	record := func(name string) completion.Middleware {
+		return func(next completion.Completer) completion.Completer {
+			return completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+				calls = append(calls, name)
+				return next.Complete(ctx, c)
+			})
+		}
+	}
+	_, _ = completion.Chain(testCompleter(nil), record("outer"), record("inner")).Complete(context.Background(), testConversation())
+	if strings.Join(calls, ",") != "outer,inner" {
+		t.Errorf("expected middleware to be called outermost first, got %v", calls)
+	}
+}
+
+func TestRetrier(t *testing.T) {
+	retrier := completion.NewRetrier().WithBackoff(time.Millisecond, 2, 5*time.Millisecond).WithMaxAttempts(3)
+	t.Run("retries retryable errors", func(t *testing.T) {
+		errs := []error{
+			&completion.StatusError{StatusCode: http.StatusTooManyRequests},
+			&completion.StatusError{StatusCode: http.StatusBadGateway},
+		}
+		attempts := 0
+		m, err := retrier.Wrap(testCompleter(func() error {
+			attempts++
+			if len(errs) == 0 {
+				return nil
+			}
+			err := errs[0]
+			errs = errs[1:]
+			return err
+		})).Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if attempts != 3 || m.Content() != "reply" {
+			t.Errorf("expected a reply after 3 attempts, got %q after %d", m.Content(), attempts)
+		}
+	})
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		attempts := 0
+		_, err := retrier.Wrap(testCompleter(func() error {
+			attempts++
+			return &completion.StatusError{StatusCode: http.StatusServiceUnavailable}
+		})).Complete(context.Background(), testConversation())
+		if !completion.IsRetryable(err) || attempts != 3 {
+			t.Errorf("expected the last error after 3 attempts, got %v after %d", err, attempts)
+		}
+	})
+	t.Run("does not retry other errors", func(t *testing.T) {
+		attempts := 0
+		_, err := retrier.Wrap(testCompleter(func() error {
+			attempts++
+			return &completion.StatusError{StatusCode: http.StatusBadRequest}
+		})).Complete(context.Background(), testConversation())
+		if err == nil || attempts != 1 {
+			t.Errorf("expected an error after 1 attempt, got %v after %d", err, attempts)
+		}
+	})
+	t.Run("honors retry after and context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := retrier.Wrap(testCompleter(func() error {
+			return &completion.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+		})).Complete(ctx, testConversation())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("expected cancellation to interrupt the retry after delay")
+		}
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("requests per minute", func(t *testing.T) {
+		limited := completion.NewRateLimiter(1, 0).Wrap(testCompleter(nil))
+		if _, err := limited.Complete(context.Background(), testConversation()); err != nil {
+			t.Fatalf("expected first request to be allowed, got %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := limited.Complete(ctx, testConversation()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected second request to wait, got %v", err)
+		}
+	})
+	t.Run("tokens per minute", func(t *testing.T) {
+		limited := completion.NewRateLimiter(0, 5).Wrap(testCompleter(nil))
+		if _, err := limited.Complete(context.Background(), testConversation()); err != nil {
+			t.Fatalf("expected first request to be allowed, got %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := limited.Complete(ctx, testConversation()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected second request to wait, got %v", err)
+		}
+	})
+	t.Run("tokens require a tokenizer", func(t *testing.T) {
+		c := conversation.New().WithMessages(message.New().WithRole("user").WithContent("hello"))
+		_, err := completion.NewRateLimiter(0, 5).Wrap(testCompleter(nil)).Complete(context.Background(), c)
+		if !errors.Is(err, message.ErrNoTokenizer) {
+			t.Errorf("expected ErrNoTokenizer, got %v", err)
+		}
+	})
+}
+
+// testConversation returns a conversation of 4 tokens.
+func testConversation() *conversation.Conversation {
+	return conversation.New().WithMessages(
+		message.New().WithRole("user").WithContent("hello there").WithTokenizer(message.TokenizerFunc(testTokenizer)),
+		message.New().WithRole("user").WithContent("anyone home?").WithTokenizer(message.TokenizerFunc(testTokenizer)),
+	)
+}
+
+// testCompleter replies with "reply", or returns the error from fail if it is non-nil.
+func testCompleter(fail func() error) completion.Completer {
+	return completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+		if fail != nil {
+			if err := fail(); err != nil {
+				return message.Message{}, err
+			}
+		}
+		return message.New().WithRole("assistant").WithContent("reply"), nil
+	})
+}
+
+func testTokenizer(content string) (tokens []int, err error) {
+	for id := range strings.Split(content, " ") {
+		tokens = append(tokens, id)
+	}
+	return
+}
@@ -0,0 +1,124 @@
+package completion
+
+import (
+	"context"
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of completions with token buckets for requests per minute and tokens per minute.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	requests *bucket
+	tokens   *bucket
+	mutex    sync.Mutex
+}
+
+// Wrap returns a completer that waits for the rate limiter before calling the given completer. It can be used as
+// Middleware.
+//
+// If a tokens per minute limit is configured, the number of tokens in each request is estimated with the
+// conversation's CountTokens method, so every message must have a tokenizer.
+func (l *RateLimiter) Wrap(next Completer) Completer {
+	return CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		var tokens int
+		if l.tokens != nil {
+			var err error
+			if tokens, err = c.CountTokens(); err != nil {
+				return message.Message{}, fmt.Errorf("could not estimate tokens: %w", err)
+			}
+		}
+		if err := l.Wait(ctx, tokens); err != nil {
+			return message.Message{}, err
+		}
+		return next.Complete(ctx, c)
+	})
+}
+
+// Wait blocks until a request for the given number of tokens is allowed, or the context is cancelled. Requests for
+// more tokens than the per-minute limit wait for a full bucket.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		wait := l.reserve(float64(tokens), time.Now())
+		if wait == 0 {
+			return nil
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a request and the tokens from the buckets if both have enough available, returning zero. Otherwise it
+// takes nothing and returns how long to wait before trying again.
+func (l *RateLimiter) reserve(tokens float64, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var wait time.Duration
+	if l.requests != nil {
+		wait = l.requests.wait(1, now)
+	}
+	if l.tokens != nil {
+		if tokens > l.tokens.capacity {
+			tokens = l.tokens.capacity
+		}
+		if w := l.tokens.wait(tokens, now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	if l.requests != nil {
+		l.requests.available--
+	}
+	if l.tokens != nil {
+		l.tokens.available -= tokens
+	}
+	return 0
+}
+
+// NewRateLimiter creates a new rate limiter allowing the given number of requests and tokens per minute. A limit of
+// zero or less is not enforced.
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	l := &RateLimiter{}
+	now := time.Now()
+	if requestsPerMinute > 0 {
+		l.requests = newBucket(float64(requestsPerMinute), now)
+	}
+	if tokensPerMinute > 0 {
+		l.tokens = newBucket(float64(tokensPerMinute), now)
+	}
+	return l
+}
+
+// bucket is a token bucket that refills its capacity once per minute.
+type bucket struct {
+	capacity  float64
+	available float64
+	last      time.Time
+}
+
+// wait refills the bucket and returns how long to wait until n is available.
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	perSecond := b.capacity / 60
+	b.available += now.Sub(b.last).Seconds() * perSecond
+	if b.available > b.capacity {
+		b.available = b.capacity
+	}
+	b.last = now
+	if b.available >= n {
+		return 0
+	}
+	if d := time.Duration((n - b.available) / perSecond * float64(time.Second)); d > 0 {
+		return d
+	}
+	return time.Nanosecond
+}
+
+func newBucket(capacity float64, now time.Time) *bucket {
+	return &bucket{capacity: capacity, available: capacity, last: now}
+}
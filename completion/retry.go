@@ -0,0 +1,117 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Retrier retries failed completions with exponential backoff and jitter.
+type Retrier struct {
+	attempts   int
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	retryable  func(error) bool
+}
+
+// Wrap returns a completer that retries the given completer. It can be used as Middleware.
+//
+// A failed completion is retried if the retryable function returns true for its error, up to the maximum number of
+// attempts. The delay before each retry grows exponentially and is reduced by a random amount up to the jitter
+// fraction. If the error is a *StatusError with a RetryAfter delay, that delay is used instead. Retries stop if the
+// context is cancelled while waiting.
+func (r Retrier) Wrap(next Completer) Completer {
+	return CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		delay := r.initial
+		for attempt := 1; ; attempt++ {
+			m, err := next.Complete(ctx, c)
+			if err == nil || attempt >= r.attempts || !r.retryable(err) {
+				return m, err
+			}
+			wait := time.Duration(float64(delay) * (1 - r.jitter*rand.Float64()))
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+				wait = statusErr.RetryAfter
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return message.Message{}, fmt.Errorf("gave up retrying after %d attempts: %w", attempt, err)
+			}
+			delay = time.Duration(float64(delay) * r.multiplier)
+			if delay > r.max {
+				delay = r.max
+			}
+		}
+	})
+}
+
+// WithMaxAttempts configures a retrier with the maximum number of attempts, including the first.
+func (r Retrier) WithMaxAttempts(n int) Retrier {
+	r.attempts = n
+	return r
+}
+
+// WithBackoff configures a retrier with the delay before the first retry, the factor the delay is multiplied by after
+// each retry, and the maximum delay.
+func (r Retrier) WithBackoff(initial time.Duration, multiplier float64, max time.Duration) Retrier {
+	r.initial = initial
+	r.multiplier = multiplier
+	r.max = max
+	return r
+}
+
+// WithJitter configures a retrier with the maximum fraction, between 0 and 1, by which each delay is randomly reduced.
+func (r Retrier) WithJitter(fraction float64) Retrier {
+	r.jitter = fraction
+	return r
+}
+
+// WithRetryable configures a retrier with the function that decides whether an error should be retried.
+func (r Retrier) WithRetryable(retryable func(error) bool) Retrier {
+	r.retryable = retryable
+	return r
+}
+
+// IsRetryable returns true if the error is a *StatusError for a 429 Too Many Requests or 5xx server error response.
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+}
+
+// NewRetrier creates a new retrier that makes up to 5 attempts, starting with a 1 second delay that doubles after each
+// retry up to 30 seconds, with up to 50% jitter, retrying errors for which IsRetryable returns true.
+func NewRetrier() Retrier {
+	r := Retrier{
+		attempts:   5,
+		initial:    time.Second,
+		max:        30 * time.Second,
+		multiplier: 2,
+		jitter:     0.5,
+		retryable:  IsRetryable,
+	}
+	return r
+}
+
+// sleep waits for the duration, returning early with the context's error if it is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
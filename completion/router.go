@@ -0,0 +1,112 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MetadataRoute is the metadata key holding the name of the route that produced a message.
+	MetadataRoute = "route"
+	// MetadataRouteTokens is the metadata key holding the number of tokens the router counted in the conversation.
+	MetadataRouteTokens = "route_tokens"
+	// MetadataRouteFailures is the metadata key holding the comma-separated names of the routes that failed before the
+	// route that produced a message.
+	MetadataRouteFailures = "route_failures"
+)
+
+// ErrNoRoute is returned when no route's context window fits a conversation.
+var ErrNoRoute = errors.New("no route fits the conversation")
+
+// Profile describes a model.
+type Profile struct {
+	// Name is the name of the model.
+	Name string
+	// ContextWindow is the maximum number of tokens the model accepts, including the tokens it generates. Zero means
+	// the context window is unknown and is not checked.
+	ContextWindow int
+}
+
+// Fits returns true if a conversation of the given number of tokens, plus the tokens reserved for the completion, fits
+// in the model's context window.
+func (p Profile) Fits(tokens, reserved int) bool {
+	return p.ContextWindow <= 0 || tokens+reserved <= p.ContextWindow
+}
+
+// route is a completer for a model.
+type route struct {
+	profile   Profile
+	completer Completer
+}
+
+// Router is a completer that chooses a model for each conversation from a list of routes, in order of preference.
+type Router struct {
+	routes   []route
+	reserved int
+}
+
+// Complete implements the Completer interface.
+//
+// The conversation is sent to the first route whose context window fits it, as counted by the conversation's
+// CountTokens method. If that route fails, the conversation is sent to the next route that fits, and so on. The name
+// of the route that produced the message, the token count, and the names of any routes that failed are recorded in
+// the message's metadata.
+func (r Router) Complete(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+	tokens, err := c.CountTokens()
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not count tokens: %w", err)
+	}
+	var failed []string
+	var errs []error
+	for _, rt := range r.routes {
+		if !rt.profile.Fits(tokens, r.reserved) {
+			continue
+		}
+		m, err := rt.completer.Complete(ctx, c)
+		if err != nil {
+			failed = append(failed, rt.profile.Name)
+			errs = append(errs, fmt.Errorf("route %q failed: %w", rt.profile.Name, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		m = m.
+			WithMetadata(MetadataRoute, rt.profile.Name).
+			WithMetadata(MetadataRouteTokens, strconv.Itoa(tokens))
+		if len(failed) > 0 {
+			m = m.WithMetadata(MetadataRouteFailures, strings.Join(failed, ","))
+		}
+		return m, nil
+	}
+	if len(errs) == 0 {
+		return message.Message{}, fmt.Errorf("%w: %d tokens plus %d reserved", ErrNoRoute, tokens, r.reserved)
+	}
+	return message.Message{}, errors.Join(errs...)
+}
+
+// WithRoute configures a router with an additional route, after any existing routes. Routes should be added in order of
+// preference, such as cheaper models before models with larger context windows, and primary providers before
+// secondary providers.
+func (r Router) WithRoute(profile Profile, c Completer) Router {
+	r.routes = append(append([]route(nil), r.routes...), route{profile: profile, completer: c})
+	return r
+}
+
+// WithReservedTokens configures a router with the number of tokens reserved for the completion when checking whether a
+// conversation fits a route's context window.
+func (r Router) WithReservedTokens(n int) Router {
+	r.reserved = n
+	return r
+}
+
+// NewRouter creates a new router with no routes.
+func NewRouter() Router {
+	r := Router{}
+	return r
+}
@@ -0,0 +1,61 @@
+package completion_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"net/http"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	unavailable := func() error { return &completion.StatusError{StatusCode: http.StatusServiceUnavailable} }
+	t.Run("routes to the first model that fits", func(t *testing.T) {
+		router := completion.NewRouter().
+			WithRoute(completion.Profile{Name: "small", ContextWindow: 5}, testCompleter(nil)).
+			WithRoute(completion.Profile{Name: "large", ContextWindow: 100}, testCompleter(nil))
+		m, err := router.Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Metadata()[completion.MetadataRoute] != "small" || m.Metadata()[completion.MetadataRouteTokens] != "4" {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+		m, err = router.WithReservedTokens(2).Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Metadata()[completion.MetadataRoute] != "large" {
+			t.Errorf("expected reserved tokens to route to the large model, got %v", m.Metadata())
+		}
+	})
+	t.Run("fails over on errors", func(t *testing.T) {
+		router := completion.NewRouter().
+			WithRoute(completion.Profile{Name: "primary"}, testCompleter(unavailable)).
+			WithRoute(completion.Profile{Name: "too small", ContextWindow: 1}, testCompleter(nil)).
+			WithRoute(completion.Profile{Name: "secondary"}, testCompleter(nil))
+		m, err := router.Complete(context.Background(), testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Metadata()[completion.MetadataRoute] != "secondary" || m.Metadata()[completion.MetadataRouteFailures] != "primary" {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+	})
+	t.Run("all routes fail", func(t *testing.T) {
+		router := completion.NewRouter().
+			WithRoute(completion.Profile{Name: "primary"}, testCompleter(unavailable)).
+			WithRoute(completion.Profile{Name: "secondary"}, testCompleter(unavailable))
+		_, err := router.Complete(context.Background(), testConversation())
+		var statusErr *completion.StatusError
+		if !errors.As(err, &statusErr) {
+			t.Errorf("expected a StatusError, got %v", err)
+		}
+	})
+	t.Run("no route fits", func(t *testing.T) {
+		router := completion.NewRouter().WithRoute(completion.Profile{Name: "small", ContextWindow: 3}, testCompleter(nil))
+		if _, err := router.Complete(context.Background(), testConversation()); !errors.Is(err, completion.ErrNoRoute) {
+			t.Errorf("expected ErrNoRoute, got %v", err)
+		}
+	})
+}
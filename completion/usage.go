@@ -0,0 +1,68 @@
+package completion
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strconv"
+)
+
+const (
+	// MetadataPromptTokens is the metadata key holding the number of tokens in the prompt of a completion.
+	MetadataPromptTokens = "prompt_tokens"
+	// MetadataCompletionTokens is the metadata key holding the number of tokens in a completion.
+	MetadataCompletionTokens = "completion_tokens"
+	// MetadataCost is the metadata key holding the dollar cost of a completion.
+	MetadataCost = "cost"
+	// MetadataUsageEstimated is the metadata key set to "true" when a completion's usage was counted with tokenizers
+	// because the completer didn't report it.
+	MetadataUsageEstimated = "usage_estimated"
+	// MetadataCompletionID is the metadata key holding a unique ID for each completion recorded by a Meter, so copies
+	// of the same reply can be told apart from separate completions with the same content.
+	MetadataCompletionID = "completion_id"
+)
+
+// Usage is the number of tokens used by a completion, as reported by the API.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// TotalTokens returns the total number of tokens used.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// WithUsage records usage in a message's metadata.
+func WithUsage(m message.Message, u Usage) message.Message {
+	return m.
+		WithMetadata(MetadataPromptTokens, strconv.Itoa(u.PromptTokens)).
+		WithMetadata(MetadataCompletionTokens, strconv.Itoa(u.CompletionTokens))
+}
+
+// UsageOf returns the usage recorded in a message's metadata. Zero usage is returned for messages without usage
+// metadata, including messages that don't provide a Metadata() map[string]string method.
+func UsageOf(m conversation.Message) Usage {
+	md, ok := m.(interface{ Metadata() map[string]string })
+	if !ok {
+		return Usage{}
+	}
+	metadata := md.Metadata()
+	prompt, _ := strconv.Atoi(metadata[MetadataPromptTokens])
+	completion, _ := strconv.Atoi(metadata[MetadataCompletionTokens])
+	return Usage{PromptTokens: prompt, CompletionTokens: completion}
+}
+
+// WithCost records a dollar cost in a message's metadata.
+func WithCost(m message.Message, cost float64) message.Message {
+	return m.WithMetadata(MetadataCost, strconv.FormatFloat(cost, 'f', -1, 64))
+}
+
+// CostOf returns the dollar cost recorded in a message's metadata, or zero if none is recorded.
+func CostOf(m conversation.Message) float64 {
+	md, ok := m.(interface{ Metadata() map[string]string })
+	if !ok {
+		return 0
+	}
+	cost, _ := strconv.ParseFloat(md.Metadata()[MetadataCost], 64)
+	return cost
+}
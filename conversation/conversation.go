@@ -2,16 +2,16 @@ package conversation
 
 import (
 	"encoding/json"
-	"fmt"
-	"strings"
 	"sync"
 )
 
 // Conversation is a collection of messages.
 type Conversation struct {
-	messages []Message
-	parent   *Conversation
-	mutex    sync.Mutex
+	messages  []Message
+	parent    *Conversation
+	children  []*Conversation
+	forkPoint int
+	mutex     sync.Mutex
 }
 
 // Messages returns the messages in the conversation.
@@ -41,15 +41,7 @@ func (c *Conversation) CountTokens() (int, error) {
 	c.mutex.Lock()
 	c.mutex.Unlock()
 	c.init()
-	var count int
-	for _, m := range c.messages {
-		tokens, err := m.Tokenize()
-		if err != nil {
-			return 0, fmt.Errorf("could not tokenize message %q: %w", m.Content(), err)
-		}
-		count += len(tokens)
-	}
-	return count, nil
+	return Messages(c.messages).CountTokens()
 }
 
 // Append appends a message to the conversation.
@@ -136,6 +128,20 @@ func (c *Conversation) Parent() *Conversation {
 	return c.parent
 }
 
+// Children returns the conversations created from this one with Fork, in the order they were created.
+func (c *Conversation) Children() []*Conversation {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*Conversation(nil), c.children...)
+}
+
+// ForkPoint returns the number of messages the parent conversation had when this conversation was created with Fork.
+func (c *Conversation) ForkPoint() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.forkPoint
+}
+
 // NewChild returns a new child conversation.
 // This is useful for creating a new conversation based on the current one, such as when a chatbot needs to "talk to itself"
 // in order to determine its response. Once the chatbot has determined its response, it can easily append the response to
@@ -146,6 +152,19 @@ func (c *Conversation) NewChild() *Conversation {
 	return New().WithParent(c)
 }
 
+// Fork returns a new child conversation like NewChild, and also records it in the parent's Children along with the
+// number of messages the parent had at the time. Use Fork for branches that belong with the conversation, such as
+// alternative replies to be exported or accounted for, and NewChild for temporary conversations, which the parent does
+// not keep a reference to.
+func (c *Conversation) Fork() *Conversation {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	child := New().WithParent(c)
+	child.forkPoint = len(c.messages)
+	c.children = append(c.children, child)
+	return child
+}
+
 // WithMessages sets the messages in the conversation.
 func (c *Conversation) WithMessages(messages ...Message) *Conversation {
 	c.mutex.Lock()
@@ -190,11 +209,12 @@ func (m Messages) Len() int {
 	return len(m)
 }
 
-// Transcript returns the messages as a string.
+// Transcript returns the messages as a string, with each message on its own line in the form "role: content".
 func (m Messages) Transcript() string {
-	var transcript string
-	for _, message := range m {
-		transcript += fmt.Sprintf("%s: %s\n", message.Role(), message.Content())
-	}
-	return strings.TrimSpace(transcript)
+	return TranscriptFormat{}.Format(m)
+}
+
+// TranscriptWith returns the messages as a string rendered with the given format.
+func (m Messages) TranscriptWith(f TranscriptFormat) string {
+	return f.Format(m)
 }
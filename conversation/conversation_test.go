@@ -153,6 +153,28 @@ func TestConversation(t *testing.T) {
 			t.Errorf("expected parent to be parent")
 		}
 	})
+	t.Run("children", func(t *testing.T) {
+		p := conversation.New()
+		first := p.Fork()
+		p.Append(testMessage{role: "user", content: "message 1"})
+		second := p.Fork()
+		children := p.Children()
+		if len(children) != 2 || children[0] != first || children[1] != second {
+			t.Errorf("expected children to be recorded in order")
+		}
+		if first.ForkPoint() != 0 {
+			t.Errorf("expected first fork point to be 0, got %d", first.ForkPoint())
+		}
+		if second.ForkPoint() != 1 {
+			t.Errorf("expected second fork point to be 1, got %d", second.ForkPoint())
+		}
+		if len(conversation.New().WithParent(p).Children()) != 0 || len(p.Children()) != 2 {
+			t.Errorf("expected WithParent not to record a child")
+		}
+		if temporary := p.NewChild(); temporary.Parent() != p || len(p.Children()) != 2 {
+			t.Errorf("expected NewChild to set the parent without recording a child")
+		}
+	})
 	t.Run("count tokens", func(t *testing.T) {
 		c := conversation.New()
 		c.Append(testMessage{role: "user", content: "message 1"})
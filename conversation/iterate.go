@@ -0,0 +1,51 @@
+package conversation
+
+// Each calls fn with the index and value of each message in the conversation, from first to last, until fn returns
+// false. The messages are a snapshot taken when Each is called, so fn may safely modify the conversation; changes are
+// not reflected in the messages fn receives.
+func (c *Conversation) Each(fn func(i int, m Message) bool) {
+	c.EachFrom(0, fn)
+}
+
+// EachFrom calls fn with the index and value of each message in the conversation, starting at index i, until fn
+// returns false. A negative index counts back from the end of the conversation. Like Each, EachFrom iterates over a
+// snapshot of the messages.
+func (c *Conversation) EachFrom(i int, fn func(i int, m Message) bool) {
+	start, messages := c.snapshot(i)
+	for j, m := range messages {
+		if !fn(start+j, m) {
+			return
+		}
+	}
+}
+
+// EachReverse calls fn with the index and value of each message in the conversation, from last to first, until fn
+// returns false. Like Each, EachReverse iterates over a snapshot of the messages.
+func (c *Conversation) EachReverse(fn func(i int, m Message) bool) {
+	_, messages := c.snapshot(0)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if !fn(i, messages[i]) {
+			return
+		}
+	}
+}
+
+// EachAncestor calls fn with the conversation's parent, then the parent's parent, and so on up to the root
+// conversation, until fn returns false.
+func (c *Conversation) EachAncestor(fn func(ancestor *Conversation) bool) {
+	for ancestor := c.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if !fn(ancestor) {
+			return
+		}
+	}
+}
+
+// snapshot returns a copy of the messages from index i onward, along with the non-negative index of the first message
+// copied. The copy is taken while holding the conversation's lock.
+func (c *Conversation) snapshot(i int) (int, Messages) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.init()
+	i = Messages(c.messages).clamp(i)
+	return i, append(Messages(nil), c.messages[i:]...)
+}
@@ -0,0 +1,110 @@
+package conversation_test
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"sync"
+	"testing"
+)
+
+func TestIterate(t *testing.T) {
+	c := conversation.New()
+	for i := 0; i < 5; i++ {
+		c.Append(testMessage{role: "user", content: fmt.Sprintf("message %d", i)})
+	}
+	collect := func(iterate func(fn func(i int, m conversation.Message) bool), stop int) []string {
+		var got []string
+		iterate(func(i int, m conversation.Message) bool {
+			got = append(got, fmt.Sprintf("%d:%s", i, m.Content()))
+			return len(got) < stop
+		})
+		return got
+	}
+	t.Run("each", func(t *testing.T) {
+		got := collect(c.Each, 100)
+		if len(got) != 5 || got[0] != "0:message 0" || got[4] != "4:message 4" {
+			t.Errorf("unexpected messages %v", got)
+		}
+	})
+	t.Run("early stop", func(t *testing.T) {
+		if got := collect(c.Each, 2); len(got) != 2 {
+			t.Errorf("expected iteration to stop after 2 messages, got %v", got)
+		}
+	})
+	t.Run("reverse", func(t *testing.T) {
+		got := collect(c.EachReverse, 2)
+		if len(got) != 2 || got[0] != "4:message 4" || got[1] != "3:message 3" {
+			t.Errorf("unexpected messages %v", got)
+		}
+	})
+	t.Run("from index", func(t *testing.T) {
+		from := func(i int) func(fn func(i int, m conversation.Message) bool) {
+			return func(fn func(i int, m conversation.Message) bool) { c.EachFrom(i, fn) }
+		}
+		if got := collect(from(3), 100); len(got) != 2 || got[0] != "3:message 3" {
+			t.Errorf("unexpected messages %v", got)
+		}
+		if got := collect(from(-1), 100); len(got) != 1 || got[0] != "4:message 4" {
+			t.Errorf("unexpected messages %v", got)
+		}
+		if got := collect(from(10), 100); len(got) != 0 {
+			t.Errorf("expected no messages, got %v", got)
+		}
+	})
+	t.Run("snapshot", func(t *testing.T) {
+		c := conversation.New().WithMessages(testMessage{role: "user", content: "first"})
+		var count int
+		c.Each(func(i int, m conversation.Message) bool {
+			c.Append(testMessage{role: "user", content: "more"})
+			count++
+			return true
+		})
+		if count != 1 || c.Messages().Len() != 2 {
+			t.Errorf("expected appended messages to be excluded from iteration, got %d", count)
+		}
+	})
+	t.Run("concurrent writers", func(t *testing.T) {
+		c := conversation.New()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					c.Append(testMessage{role: "user", content: "message"})
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					c.EachReverse(func(i int, m conversation.Message) bool { return m != nil })
+				}
+			}()
+		}
+		wg.Wait()
+		if got := len(collect(c.Each, 1000)); got != 400 {
+			t.Errorf("expected 400 messages, got %d", got)
+		}
+	})
+	t.Run("ancestors", func(t *testing.T) {
+		root := conversation.New()
+		child := root.NewChild()
+		grandchild := child.NewChild()
+		var ancestors []*conversation.Conversation
+		grandchild.EachAncestor(func(ancestor *conversation.Conversation) bool {
+			ancestors = append(ancestors, ancestor)
+			return true
+		})
+		if len(ancestors) != 2 || ancestors[0] != child || ancestors[1] != root {
+			t.Errorf("unexpected ancestors %v", ancestors)
+		}
+		var count int
+		grandchild.EachAncestor(func(*conversation.Conversation) bool {
+			count++
+			return false
+		})
+		if count != 1 {
+			t.Errorf("expected iteration to stop after the parent, got %d", count)
+		}
+	})
+}
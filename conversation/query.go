@@ -0,0 +1,110 @@
+package conversation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter returns the messages for which fn returns true.
+func (m Messages) Filter(fn func(Message) bool) Messages {
+	var filtered Messages
+	for _, msg := range m {
+		if fn(msg) {
+			filtered = append(filtered, msg)
+		}
+	}
+	return filtered
+}
+
+// ByRole returns the messages sent from any of the given roles.
+func (m Messages) ByRole(roles ...string) Messages {
+	return m.Filter(func(msg Message) bool {
+		for _, role := range roles {
+			if msg.Role() == role {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Contains returns the messages whose content contains the given substring.
+func (m Messages) Contains(substr string) Messages {
+	return m.Filter(func(msg Message) bool {
+		return strings.Contains(msg.Content(), substr)
+	})
+}
+
+// Match returns the messages whose content matches the given regular expression.
+func (m Messages) Match(re *regexp.Regexp) Messages {
+	return m.Filter(func(msg Message) bool {
+		return re.MatchString(msg.Content())
+	})
+}
+
+// Map returns the result of calling fn on each message.
+func (m Messages) Map(fn func(Message) Message) Messages {
+	if m == nil {
+		return nil
+	}
+	mapped := make(Messages, len(m))
+	for i, msg := range m {
+		mapped[i] = fn(msg)
+	}
+	return mapped
+}
+
+// Slice returns a copy of the messages from start up to but not including end. Negative indices count back from the
+// end, so Slice(-2, Len()) returns the last two messages. Indices outside the range of messages are clamped to it.
+func (m Messages) Slice(start, end int) Messages {
+	start, end = m.clamp(start), m.clamp(end)
+	if start >= end {
+		return Messages{}
+	}
+	return append(Messages{}, m[start:end]...)
+}
+
+// clamp converts a possibly negative index into one within the range of messages.
+func (m Messages) clamp(i int) int {
+	if i < 0 {
+		i += len(m)
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > len(m) {
+		return len(m)
+	}
+	return i
+}
+
+// First returns the first message, or nil if there are no messages.
+func (m Messages) First() Message {
+	if len(m) == 0 {
+		return nil
+	}
+	return m[0]
+}
+
+// Last returns the last message, or nil if there are no messages. Combine it with ByRole to find the last message from
+// a role, as in ByRole("user").Last().
+func (m Messages) Last() Message {
+	if len(m) == 0 {
+		return nil
+	}
+	return m[len(m)-1]
+}
+
+// CountTokens returns the number of tokens in the messages.
+func (m Messages) CountTokens() (int, error) {
+	var count int
+	for _, msg := range m {
+		tokens, err := msg.Tokenize()
+		if err != nil {
+			return 0, fmt.Errorf("could not tokenize message %q: %w", msg.Content(), err)
+		}
+		count += len(tokens)
+	}
+	return count, nil
+}
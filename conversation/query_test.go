@@ -0,0 +1,90 @@
+package conversation_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	messages := conversation.Messages{
+		testMessage{role: "system", content: "Be brief."},
+		testMessage{role: "user", content: "What is 2 + 2?"},
+		testMessage{role: "assistant", content: "4"},
+		testMessage{role: "user", content: "And 3 + 3?"},
+		testMessage{role: "assistant", content: "6"},
+	}
+	t.Run("by role", func(t *testing.T) {
+		got := messages.ByRole("user")
+		if got.Transcript() != "user: What is 2 + 2?\nuser: And 3 + 3?" {
+			t.Errorf("unexpected messages %q", got.Transcript())
+		}
+		if messages.ByRole("tool").Len() != 0 {
+			t.Errorf("expected no tool messages")
+		}
+	})
+	t.Run("first and last", func(t *testing.T) {
+		if last := messages.ByRole("user").Last(); last == nil || last.Content() != "And 3 + 3?" {
+			t.Errorf("unexpected last user message %v", last)
+		}
+		if first := messages.ByRole("assistant").First(); first == nil || first.Content() != "4" {
+			t.Errorf("unexpected first assistant message %v", first)
+		}
+		if messages.ByRole("tool").Last() != nil || messages.ByRole("tool").First() != nil {
+			t.Errorf("expected nil for empty messages")
+		}
+	})
+	t.Run("slice", func(t *testing.T) {
+		tests := []struct {
+			start, end int
+			want       string
+		}{
+			{start: 1, end: 3, want: "user: What is 2 + 2?\nassistant: 4"},
+			{start: -2, end: messages.Len(), want: "user: And 3 + 3?\nassistant: 6"},
+			{start: 3, end: 100, want: "user: And 3 + 3?\nassistant: 6"},
+			{start: -100, end: 1, want: "system: Be brief."},
+			{start: 3, end: 1, want: ""},
+		}
+		for _, tt := range tests {
+			if got := messages.Slice(tt.start, tt.end).Transcript(); got != tt.want {
+				t.Errorf("Slice(%d, %d): expected %q, got %q", tt.start, tt.end, tt.want, got)
+			}
+		}
+		sliced := messages.Slice(0, 1)
+		sliced[0] = testMessage{role: "user", content: "changed"}
+		if messages[0].Content() != "Be brief." {
+			t.Errorf("expected slice to be a copy")
+		}
+	})
+	t.Run("search", func(t *testing.T) {
+		if got := messages.Contains("+").Len(); got != 2 {
+			t.Errorf("expected 2 messages containing +, got %d", got)
+		}
+		if got := messages.Match(regexp.MustCompile(`^\d+$`)).Transcript(); got != "assistant: 4\nassistant: 6" {
+			t.Errorf("unexpected matches %q", got)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		upper := messages.ByRole("system").Map(func(m conversation.Message) conversation.Message {
+			return testMessage{role: m.Role(), content: strings.ToUpper(m.Content())}
+		})
+		if upper.Transcript() != "system: BE BRIEF." {
+			t.Errorf("unexpected messages %q", upper.Transcript())
+		}
+	})
+	t.Run("count tokens", func(t *testing.T) {
+		count, err := messages.ByRole("user").CountTokens()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if count != 9 {
+			t.Errorf("expected 9 tokens, got %d", count)
+		}
+		failing := append(messages.Slice(0, 1), testMessage{role: "user", tokenizeError: errTokenizing})
+		if _, err := failing.CountTokens(); !errors.Is(err, errTokenizing) {
+			t.Errorf("expected error to be %v, got %v", errTokenizing, err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TranscriptFormat configures how messages are rendered as a transcript.
+// The zero value renders each message as "role: content" on its own line.
+type TranscriptFormat struct {
+	labels          map[string]string
+	excluded        map[string]bool
+	separator       *string
+	timestampLayout string
+	names           bool
+	maxLength       int
+	ellipsis        string
+	untrimmed       bool
+}
+
+// Format returns the messages as a transcript.
+func (f TranscriptFormat) Format(messages Messages) string {
+	separator := "\n"
+	if f.separator != nil {
+		separator = *f.separator
+	}
+	var lines []string
+	for _, m := range messages {
+		if f.excluded[m.Role()] {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", f.header(m), f.truncate(m.Content())))
+	}
+	transcript := strings.Join(lines, separator)
+	if f.untrimmed {
+		return transcript
+	}
+	return strings.TrimSpace(transcript)
+}
+
+// header returns the label that precedes a message's content, including its timestamp and name if configured.
+func (f TranscriptFormat) header(m Message) string {
+	header := m.Role()
+	if label, ok := f.labels[m.Role()]; ok {
+		header = label
+	}
+	if named, ok := m.(interface{ Name() string }); ok && f.names && named.Name() != "" {
+		header = fmt.Sprintf("%s (%s)", header, named.Name())
+	}
+	if timestamped, ok := m.(interface{ Timestamp() time.Time }); ok && f.timestampLayout != "" && !timestamped.Timestamp().IsZero() {
+		header = fmt.Sprintf("[%s] %s", timestamped.Timestamp().Format(f.timestampLayout), header)
+	}
+	return header
+}
+
+// truncate shortens content to the maximum length, if configured.
+func (f TranscriptFormat) truncate(content string) string {
+	if f.maxLength <= 0 {
+		return content
+	}
+	runes := []rune(content)
+	if len(runes) <= f.maxLength {
+		return content
+	}
+	return string(runes[:f.maxLength]) + f.ellipsis
+}
+
+// WithRoleLabel configures a transcript format with the label shown in place of a role, such as "Human" for "user".
+func (f TranscriptFormat) WithRoleLabel(role, label string) TranscriptFormat {
+	labels := make(map[string]string, len(f.labels)+1)
+	for k, v := range f.labels {
+		labels[k] = v
+	}
+	labels[role] = label
+	f.labels = labels
+	return f
+}
+
+// WithoutRoles configures a transcript format to omit messages from the given roles, such as "system".
+func (f TranscriptFormat) WithoutRoles(roles ...string) TranscriptFormat {
+	excluded := make(map[string]bool, len(f.excluded)+len(roles))
+	for k, v := range f.excluded {
+		excluded[k] = v
+	}
+	for _, role := range roles {
+		excluded[role] = true
+	}
+	f.excluded = excluded
+	return f
+}
+
+// WithSeparator configures a transcript format with the separator placed between messages. The default is a newline.
+func (f TranscriptFormat) WithSeparator(separator string) TranscriptFormat {
+	f.separator = &separator
+	return f
+}
+
+// WithNames configures whether a transcript format includes the names of the participants that sent each message.
+// Names are only shown for messages that provide a Name() string method.
+func (f TranscriptFormat) WithNames(names bool) TranscriptFormat {
+	f.names = names
+	return f
+}
+
+// WithTimestamps configures a transcript format to prefix each message with its timestamp, formatted with the given
+// layout. Timestamps are only shown for messages that provide a Timestamp() time.Time method.
+func (f TranscriptFormat) WithTimestamps(layout string) TranscriptFormat {
+	f.timestampLayout = layout
+	return f
+}
+
+// WithMaxLength configures a transcript format to truncate message content longer than the given number of characters,
+// appending the ellipsis to truncated content.
+func (f TranscriptFormat) WithMaxLength(n int, ellipsis string) TranscriptFormat {
+	f.maxLength = n
+	f.ellipsis = ellipsis
+	return f
+}
+
+// WithTrimSpace configures whether leading and trailing whitespace is trimmed from the transcript. The default is true.
+func (f TranscriptFormat) WithTrimSpace(trim bool) TranscriptFormat {
+	f.untrimmed = !trim
+	return f
+}
@@ -0,0 +1,77 @@
+package conversation_test
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"testing"
+	"time"
+)
+
+func TestTranscript(t *testing.T) {
+	sent := time.Date(2023, 5, 26, 12, 30, 0, 0, time.UTC)
+	messages := conversation.Messages{
+		message.New().WithRole("system").WithContent("Be brief."),
+		message.New().WithRole("user").WithContent(" Hello there! ").WithName("alice").WithTimestamp(sent),
+		message.New().WithRole("assistant").WithContent("Hi!").WithTimestamp(sent.Add(time.Minute)),
+	}
+	tests := []struct {
+		name   string
+		format conversation.TranscriptFormat
+		want   string
+	}{
+		{
+			name:   "default",
+			format: conversation.TranscriptFormat{},
+			want:   "system: Be brief.\nuser:  Hello there! \nassistant: Hi!",
+		},
+		{
+			name:   "role labels",
+			format: conversation.TranscriptFormat{}.WithRoleLabel("user", "Human").WithRoleLabel("assistant", "AI"),
+			want:   "system: Be brief.\nHuman:  Hello there! \nAI: Hi!",
+		},
+		{
+			name:   "without roles",
+			format: conversation.TranscriptFormat{}.WithoutRoles("system"),
+			want:   "user:  Hello there! \nassistant: Hi!",
+		},
+		{
+			name:   "separator",
+			format: conversation.TranscriptFormat{}.WithSeparator("\n\n"),
+			want:   "system: Be brief.\n\nuser:  Hello there! \n\nassistant: Hi!",
+		},
+		{
+			name:   "names and timestamps",
+			format: conversation.TranscriptFormat{}.WithNames(true).WithTimestamps("15:04"),
+			want:   "system: Be brief.\n[12:30] user (alice):  Hello there! \n[12:31] assistant: Hi!",
+		},
+		{
+			name:   "max length",
+			format: conversation.TranscriptFormat{}.WithMaxLength(5, "…"),
+			want:   "system: Be br…\nuser:  Hell…\nassistant: Hi!",
+		},
+		{
+			name:   "untrimmed",
+			format: conversation.TranscriptFormat{}.WithoutRoles("system", "user").WithSeparator("\n").WithTrimSpace(false),
+			want:   "assistant: Hi!",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messages.TranscriptWith(tt.format); got != tt.want {
+				t.Errorf("expected transcript to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+	t.Run("transcript matches default format", func(t *testing.T) {
+		if messages.Transcript() != messages.TranscriptWith(conversation.TranscriptFormat{}) {
+			t.Errorf("expected Transcript to use the default format")
+		}
+	})
+	t.Run("messages without names or timestamps", func(t *testing.T) {
+		m := conversation.Messages{testMessage{role: "user", content: "hello"}}
+		got := m.TranscriptWith(conversation.TranscriptFormat{}.WithNames(true).WithTimestamps(time.RFC3339))
+		if got != "user: hello" {
+			t.Errorf("expected transcript to be %q, got %q", "user: hello", got)
+		}
+	})
+}
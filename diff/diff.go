@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/internal/lcs"
+	"regexp"
+	"strings"
+)
+
+// Op is the kind of difference between two messages or two words.
+type Op string
+
+const (
+	// OpEqual marks a message or words that appear in both conversations.
+	OpEqual Op = "equal"
+	// OpInsert marks a message or words that only appear in the new conversation.
+	OpInsert Op = "insert"
+	// OpDelete marks a message or words that only appear in the old conversation.
+	OpDelete Op = "delete"
+	// OpEdit marks a message whose content changed between the conversations.
+	OpEdit Op = "edit"
+)
+
+// Change describes how one message differs between the old and new conversations.
+type Change struct {
+	Op Op `json:"op"`
+	// OldIndex is the index of the message in the old conversation, or -1 for inserted messages.
+	OldIndex int `json:"old_index"`
+	// NewIndex is the index of the message in the new conversation, or -1 for deleted messages.
+	NewIndex int    `json:"new_index"`
+	Role     string `json:"role"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	// Words is the word-level diff of the old and new content of edited messages.
+	Words []Word `json:"words,omitempty"`
+}
+
+// Word is a run of words and whitespace within the word-level diff of an edited message.
+type Word struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff is the list of changes that turns one conversation into another, including the messages they share.
+// A Diff can be marshaled to JSON directly.
+type Diff []Change
+
+// Changed returns the changes other than messages that appear in both conversations.
+func (d Diff) Changed() Diff {
+	var changed Diff
+	for _, c := range d {
+		if c.Op != OpEqual {
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// Unified returns the diff as text, with one line per message line prefixed by " " for shared messages, "-" for
+// deleted messages, "+" for inserted messages and "~" for edited messages. Within edited messages, deleted words are
+// shown as [-words-] and inserted words as {+words+}.
+func (d Diff) Unified() string {
+	var b strings.Builder
+	for _, c := range d {
+		switch c.Op {
+		case OpEqual:
+			writeLines(&b, " ", c.Role, c.New)
+		case OpDelete:
+			writeLines(&b, "-", c.Role, c.Old)
+		case OpInsert:
+			writeLines(&b, "+", c.Role, c.New)
+		case OpEdit:
+			var content strings.Builder
+			for _, w := range c.Words {
+				switch w.Op {
+				case OpDelete:
+					fmt.Fprintf(&content, "[-%s-]", w.Text)
+				case OpInsert:
+					fmt.Fprintf(&content, "{+%s+}", w.Text)
+				default:
+					content.WriteString(w.Text)
+				}
+			}
+			writeLines(&b, "~", c.Role, content.String())
+		}
+	}
+	return b.String()
+}
+
+// writeLines writes a message as "prefix role: content", repeating the prefix on each line of multi-line content.
+func writeLines(b *strings.Builder, prefix, role, content string) {
+	for i, line := range strings.Split(content, "\n") {
+		if i == 0 {
+			fmt.Fprintf(b, "%s %s: %s\n", prefix, role, line)
+			continue
+		}
+		fmt.Fprintf(b, "%s %s\n", prefix, line)
+	}
+}
+
+// Conversations returns the diff between the messages of two conversations, such as a parent and its child.
+func Conversations(before, after *conversation.Conversation) Diff {
+	return Messages(before.Messages(), after.Messages())
+}
+
+// Messages returns the diff between two lists of messages. Messages are matched by role and content. A deleted message
+// immediately followed by an inserted message from the same role is reported as an edit.
+func Messages(before, after conversation.Messages) Diff {
+	edits := lcs.Align(len(before), len(after), func(i, j int) bool {
+		return before[i].Role() == after[j].Role() && before[i].Content() == after[j].Content()
+	})
+	var d Diff
+	for k := 0; k < len(edits); {
+		if edits[k].Op == lcs.Equal {
+			i, j := edits[k].I, edits[k].J
+			d = append(d, Change{Op: OpEqual, OldIndex: i, NewIndex: j, Role: after[j].Role(), Old: before[i].Content(), New: after[j].Content()})
+			k++
+			continue
+		}
+		var deleted, inserted []lcs.Edit
+		for ; k < len(edits) && edits[k].Op != lcs.Equal; k++ {
+			if edits[k].Op == lcs.Delete {
+				deleted = append(deleted, edits[k])
+			} else {
+				inserted = append(inserted, edits[k])
+			}
+		}
+		d = append(d, pair(before, after, deleted, inserted)...)
+	}
+	return d
+}
+
+// pair turns a run of deleted and inserted messages into changes, reporting the n-th deleted and n-th inserted
+// messages as an edit when they share a role.
+func pair(before, after conversation.Messages, deleted, inserted []lcs.Edit) Diff {
+	var d Diff
+	n := 0
+	for ; n < len(deleted) && n < len(inserted); n++ {
+		o, m := before[deleted[n].I], after[inserted[n].J]
+		if o.Role() != m.Role() {
+			break
+		}
+		d = append(d, Change{
+			Op:       OpEdit,
+			OldIndex: deleted[n].I,
+			NewIndex: inserted[n].J,
+			Role:     m.Role(),
+			Old:      o.Content(),
+			New:      m.Content(),
+			Words:    Words(o.Content(), m.Content()),
+		})
+	}
+	for _, e := range deleted[n:] {
+		d = append(d, Change{Op: OpDelete, OldIndex: e.I, NewIndex: -1, Role: before[e.I].Role(), Old: before[e.I].Content()})
+	}
+	for _, e := range inserted[n:] {
+		d = append(d, Change{Op: OpInsert, OldIndex: -1, NewIndex: e.J, Role: after[e.J].Role(), New: after[e.J].Content()})
+	}
+	return d
+}
+
+// words splits text into words along with the whitespace that follows them. Leading whitespace is a word of its own.
+var words = regexp.MustCompile(`\S+\s*|\s+`)
+
+// Words returns the word-level diff between two strings. Consecutive words with the same op are merged into one Word.
+func Words(before, after string) []Word {
+	a, b := words.FindAllString(before, -1), words.FindAllString(after, -1)
+	var result []Word
+	for _, e := range lcs.Align(len(a), len(b), func(i, j int) bool { return a[i] == b[j] }) {
+		var text string
+		if e.Op == lcs.Insert {
+			text = b[e.J]
+		} else {
+			text = a[e.I]
+		}
+		if n := len(result); n > 0 && result[n-1].Op == ops[e.Op] {
+			result[n-1].Text += text
+			continue
+		}
+		result = append(result, Word{Op: ops[e.Op], Text: text})
+	}
+	return result
+}
+
+// ops maps the steps of an alignment to the ops reported in diffs.
+var ops = map[lcs.Op]Op{lcs.Equal: OpEqual, lcs.Delete: OpDelete, lcs.Insert: OpInsert}
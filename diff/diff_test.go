@@ -0,0 +1,116 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/diff"
+	"github.com/bradfair/chat/message"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	before := conversation.Messages{
+		message.New().WithRole("system").WithContent("Be brief."),
+		message.New().WithRole("user").WithContent("What is the capital of France?"),
+		message.New().WithRole("assistant").WithContent("It is Paris."),
+		message.New().WithRole("user").WithContent("Thanks!"),
+	}
+	after := conversation.Messages{
+		message.New().WithRole("user").WithContent("What is the capital of France?"),
+		message.New().WithRole("assistant").WithContent("The capital is Paris."),
+		message.New().WithRole("user").WithContent("Thanks!"),
+		message.New().WithRole("assistant").WithContent("You're welcome."),
+	}
+	d := diff.Messages(before, after)
+	t.Run("changes", func(t *testing.T) {
+		var ops []diff.Op
+		for _, c := range d {
+			ops = append(ops, c.Op)
+		}
+		want := []diff.Op{diff.OpDelete, diff.OpEqual, diff.OpEdit, diff.OpEqual, diff.OpInsert}
+		if !reflect.DeepEqual(ops, want) {
+			t.Fatalf("expected ops %v, got %v", want, ops)
+		}
+		if d[0].OldIndex != 0 || d[0].NewIndex != -1 || d[4].OldIndex != -1 || d[4].NewIndex != 3 {
+			t.Errorf("unexpected indices %+v %+v", d[0], d[4])
+		}
+		if len(d.Changed()) != 3 {
+			t.Errorf("expected 3 changed messages, got %d", len(d.Changed()))
+		}
+	})
+	t.Run("words", func(t *testing.T) {
+		want := []diff.Word{
+			{Op: diff.OpDelete, Text: "It "},
+			{Op: diff.OpInsert, Text: "The capital "},
+			{Op: diff.OpEqual, Text: "is Paris."},
+		}
+		if !reflect.DeepEqual(d[2].Words, want) {
+			t.Errorf("expected words %+v, got %+v", want, d[2].Words)
+		}
+	})
+	t.Run("unified", func(t *testing.T) {
+		want := "- system: Be brief.\n" +
+			"  user: What is the capital of France?\n" +
+			"~ assistant: [-It -]{+The capital +}is Paris.\n" +
+			"  user: Thanks!\n" +
+			"+ assistant: You're welcome.\n"
+		if got := d.Unified(); got != want {
+			t.Errorf("expected unified diff to be %q, got %q", want, got)
+		}
+	})
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(d[2:3])
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := `[{"op":"edit","old_index":2,"new_index":1,"role":"assistant","old":"It is Paris.","new":"The capital is Paris.",` +
+			`"words":[{"op":"delete","text":"It "},{"op":"insert","text":"The capital "},{"op":"equal","text":"is Paris."}]}]`
+		if string(b) != want {
+			t.Errorf("expected json to be %s, got %s", want, b)
+		}
+	})
+	t.Run("role change is not an edit", func(t *testing.T) {
+		d := diff.Messages(
+			conversation.Messages{message.New().WithRole("user").WithContent("Hi")},
+			conversation.Messages{message.New().WithRole("assistant").WithContent("Hi")},
+		)
+		if len(d) != 2 || d[0].Op != diff.OpDelete || d[1].Op != diff.OpInsert {
+			t.Errorf("unexpected diff %+v", d)
+		}
+	})
+	t.Run("multi-line content", func(t *testing.T) {
+		d := diff.Messages(nil, conversation.Messages{message.New().WithRole("user").WithContent("one\ntwo")})
+		if got := d.Unified(); got != "+ user: one\n+ two\n" {
+			t.Errorf("unexpected unified diff %q", got)
+		}
+	})
+}
+
+func TestConversations(t *testing.T) {
+	parent := conversation.New().WithMessages(message.New().WithRole("user").WithContent("Hello!"))
+	child := parent.NewChild().WithMessages(parent.Messages()...)
+	child.Append(message.New().WithRole("system").WithContent("Summarize the conversation."))
+	d := diff.Conversations(parent, child)
+	if len(d) != 2 || d[0].Op != diff.OpEqual || d[1].Op != diff.OpInsert {
+		t.Errorf("unexpected diff %+v", d)
+	}
+	if len(diff.Conversations(parent, parent).Changed()) != 0 {
+		t.Errorf("expected no changes between a conversation and itself")
+	}
+}
+
+func TestWordsLarge(t *testing.T) {
+	before := "Start " + strings.Repeat("old ", 20000) + "end."
+	after := "Start " + strings.Repeat("new ", 20000) + "end."
+	want := []diff.Word{
+		{Op: diff.OpEqual, Text: "Start "},
+		{Op: diff.OpDelete, Text: strings.Repeat("old ", 20000)},
+		{Op: diff.OpInsert, Text: strings.Repeat("new ", 20000)},
+		{Op: diff.OpEqual, Text: "end."},
+	}
+	if got := diff.Words(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the differing middle to be replaced as a whole, got %d words", len(got))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/bradfair/chat/conversation"
 	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/prompt"
 	"github.com/fatih/color"
 	"github.com/sashabaranov/go-openai"
 	"log"
@@ -53,13 +54,32 @@ func main() {
 	}
 }
 
+var monologuePrompt = prompt.Must(prompt.Parse("monologue", `You are an AI that serves as the internal monologue of a curious and charismatic chatbot.
+Here's a transcript of a conversation you're having with a human. You're 'assistant':
+
+{{ transcript .Conversation }}
+We have strict rules for handling conversations:
+1. Stay on topic: you are a curious and charismatic chatbot, and you do not talk about your internal monologue.
+2. Be respectful. Don't allow the conversation to become hostile.
+3. Be safe. Don't allow the conversation to become dangerous.
+4. Be honest. Don't lie or mislead.
+1) Provide some thoughts about the conversation so far.
+2) List the overall and current goals of each participant. 3) Abide by the rules. What is the best response to the most recent message?
+4) Critique (3). How could it be better?
+
+1)`)).WithRole(message.RoleSystem).WithVariable("Conversation", prompt.Conversation)
+
+var respondPrompt = prompt.Must(prompt.Parse("respond", `With your self-critique in mind, briefly respond directly to {{ printf "%q" (last .Conversation).Content }}:`)).
+	WithVariable("Conversation", prompt.Conversation)
+
 func ThinkAndRespond(openAiKey string, originalConversation *conversation.Conversation) string {
-	prompt := "You are an AI that serves as the internal monologue of a curious and charismatic chatbot."
-	transcript := "Here's a transcript of a conversation you're having with a human. You're 'assistant':\n\n" + originalConversation.Messages().Transcript()
-	rules := "We have strict rules for handling conversations:\n1. Stay on topic: you are a curious and charismatic chatbot, and you do not talk about your internal monologue.\n2. Be respectful. Don't allow the conversation to become hostile.\n3. Be safe. Don't allow the conversation to become dangerous.\n4. Be honest. Don't lie or mislead."
-	request := "1) Provide some thoughts about the conversation so far.\n2) List the overall and current goals of each participant. 3) Abide by the rules. What is the best response to the most recent message?\n4) Critique (3). How could it be better?\n\n1)"
+	vars := prompt.Vars{"Conversation": originalConversation}
+	monologue, err := monologuePrompt.Render(vars)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	internalMonologue := originalConversation.NewChild()
-	internalMonologue.Append(message.New().WithRole("system").WithContent(fmt.Sprintf("%s\n%s\n%s\n%s", prompt, transcript, rules, request)))
+	internalMonologue.Append(monologue)
 	assistantResponse, err := getCompletion(openAiKey, internalMonologue)
 	if err != nil {
 		log.Fatalln(err)
@@ -68,7 +88,11 @@ func ThinkAndRespond(openAiKey string, originalConversation *conversation.Conver
 	fmt.Println("Chatbot (internal monologue): " + assistantResponse)
 	color.Unset()
 	internalMonologue.Append(message.New().WithRole("assistant").WithContent(assistantResponse))
-	internalMonologue.Append(message.New().WithRole("user").WithContent(fmt.Sprintf("With your self-critique in mind, briefly respond directly to %q:", originalConversation.Messages()[len(originalConversation.Messages())-1].Content())))
+	respond, err := respondPrompt.Render(vars)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	internalMonologue.Append(respond)
 	assistantResponse, err = getCompletion(openAiKey, internalMonologue)
 	if err != nil {
 		log.Fatalln(err)
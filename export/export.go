@@ -0,0 +1,83 @@
+package export
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"unicode"
+)
+
+// section is a contiguous run of a conversation's messages, followed by the children that forked from it.
+type section struct {
+	messages conversation.Messages
+	children []*conversation.Conversation
+}
+
+// sections splits a conversation's messages, starting at the given index, at the fork points of its children, so that
+// each child can be rendered beneath the message it forked from.
+func sections(c *conversation.Conversation, start int) []section {
+	messages := c.Messages()
+	forks := make(map[int][]*conversation.Conversation)
+	for _, child := range c.Children() {
+		point := child.ForkPoint()
+		if point < start {
+			point = start
+		}
+		if point > len(messages) {
+			point = len(messages)
+		}
+		forks[point] = append(forks[point], child)
+	}
+	var result []section
+	for i := start; i <= len(messages); i++ {
+		if children, ok := forks[i]; ok || i == len(messages) {
+			result = append(result, section{messages: messages[start:i], children: children})
+			start = i
+		}
+	}
+	return result
+}
+
+// shared returns the number of leading messages a child conversation shares with its parent before the fork point.
+// Children are commonly created with Fork().WithMessages(parent.Messages()...), and these copies are not rendered
+// again beneath the fork point.
+func shared(child *conversation.Conversation) int {
+	if child.Parent() == nil {
+		return 0
+	}
+	messages := child.Messages()
+	parent := child.Parent().Messages()
+	if point := child.ForkPoint(); point < len(parent) {
+		parent = parent[:point]
+	}
+	i := 0
+	for i < len(messages) && i < len(parent) && messages[i].Role() == parent[i].Role() && messages[i].Content() == parent[i].Content() {
+		i++
+	}
+	return i
+}
+
+// heading returns the heading shown above a message, such as "User" or "User (alice)".
+func heading(m conversation.Message) string {
+	role := []rune(m.Role())
+	if len(role) > 0 {
+		role[0] = unicode.ToUpper(role[0])
+	}
+	heading := string(role)
+	if named, ok := m.(interface{ Name() string }); ok && named.Name() != "" {
+		heading += " (" + named.Name() + ")"
+	}
+	return heading
+}
+
+// summary returns the label of the collapsible section a child conversation is rendered in.
+func summary(child *conversation.Conversation) string {
+	n := 1
+	if parent := child.Parent(); parent != nil {
+		for i, sibling := range parent.Children() {
+			if sibling == child {
+				n = i + 1
+			}
+		}
+	}
+	return fmt.Sprintf("Child conversation %d (forked after message %d)", n, child.ForkPoint())
+}
@@ -0,0 +1,66 @@
+package export_test
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/export"
+	"github.com/bradfair/chat/message"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Run("messages", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("user").WithContent("Show me some code.").WithName("alice"),
+			message.New().WithRole("assistant").WithContent("```go\nfmt.Println(\"hi\")\n```"),
+		)
+		want := "## User (alice)\n\nShow me some code.\n\n## Assistant\n\n```go\nfmt.Println(\"hi\")\n```\n"
+		if got := export.Markdown(c); got != want {
+			t.Errorf("expected markdown to be %q, got %q", want, got)
+		}
+	})
+	t.Run("children", func(t *testing.T) {
+		c := testConversation()
+		want := "## User\n\nHello!\n\n" +
+			"<details>\n<summary>Child conversation 1 (forked after message 1)</summary>\n\n" +
+			"### System\n\nSummarize the conversation.\n\n" +
+			"</details>\n\n" +
+			"## Assistant\n\nHi there!"
+		if got := export.Markdown(c); got != want+"\n" {
+			t.Errorf("expected markdown to be %q, got %q", want+"\n", got)
+		}
+	})
+}
+
+func TestHTML(t *testing.T) {
+	c := testConversation()
+	c.Append(message.New().WithRole("user").WithContent("<script>alert(1)</script>"))
+	got := export.HTML(c, "Support <chat>")
+	for _, want := range []string{
+		"<title>Support &lt;chat&gt;</title>",
+		"<div class=\"message user\">\n<div class=\"role\">User</div>\n<div class=\"content\">Hello!</div>\n</div>\n" +
+			"<details>\n<summary>Child conversation 1 (forked after message 1)</summary>\n<div class=\"conversation\">\n" +
+			"<div class=\"message system\">\n<div class=\"role\">System</div>\n<div class=\"content\">Summarize the conversation.</div>\n</div>\n" +
+			"</div>\n</details>\n" +
+			"<div class=\"message assistant\">",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected html to contain %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") {
+		t.Errorf("expected content to be escaped")
+	}
+}
+
+// testConversation returns a conversation with a child forked after its first message. The child copies the parent's
+// first message, which is not rendered again beneath the fork point.
+func testConversation() *conversation.Conversation {
+	c := conversation.New()
+	c.Append(message.New().WithRole("user").WithContent("Hello!"))
+	child := c.Fork().WithMessages(c.Messages()...)
+	child.Append(message.New().WithRole("system").WithContent("Summarize the conversation."))
+	c.Append(message.New().WithRole("assistant").WithContent("Hi there!"))
+	return c
+}
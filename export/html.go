@@ -0,0 +1,72 @@
+package export
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// htmlHeader is the start of a standalone HTML document, including the styles for message bubbles.
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; background: #f5f5f5; }
+.message { margin: 0.5rem 0; padding: 0.75rem 1rem; border-radius: 1rem; background: #fff; max-width: 80%%; }
+.message .role { font-size: 0.75rem; font-weight: bold; color: #666; margin-bottom: 0.25rem; }
+.message .content { white-space: pre-wrap; }
+.message.user { margin-left: auto; background: #dcf0ff; }
+.message.system { max-width: 100%%; background: #eee; font-style: italic; }
+details { margin: 0.5rem 0 0.5rem 1.5rem; padding-left: 1rem; border-left: 2px solid #ccc; }
+summary { cursor: pointer; color: #666; }
+</style>
+</head>
+<body>
+<div class="conversation">
+`
+
+// htmlFooter is the end of a standalone HTML document.
+const htmlFooter = `</div>
+</body>
+</html>
+`
+
+// HTML returns the conversation as a standalone HTML document, with each message in a styled bubble and all content
+// escaped. Child conversations created with Fork are rendered as collapsible sections beneath the message they
+// forked from.
+func HTML(c *conversation.Conversation, title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, htmlHeader, html.EscapeString(title))
+	writeHTML(&b, c, 0)
+	b.WriteString(htmlFooter)
+	return b.String()
+}
+
+func writeHTML(b *strings.Builder, c *conversation.Conversation, start int) {
+	for _, s := range sections(c, start) {
+		for _, m := range s.messages {
+			fmt.Fprintf(b, "<div class=\"message %s\">\n<div class=\"role\">%s</div>\n<div class=\"content\">%s</div>\n</div>\n",
+				class(m.Role()), html.EscapeString(heading(m)), html.EscapeString(m.Content()))
+		}
+		for _, child := range s.children {
+			fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n<div class=\"conversation\">\n", html.EscapeString(summary(child)))
+			writeHTML(b, child, shared(child))
+			b.WriteString("</div>\n</details>\n")
+		}
+	}
+}
+
+// class returns a role as a CSS class name, replacing any characters other than letters, digits, hyphens and
+// underscores with hyphens.
+func class(role string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, role)
+}
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"strings"
+)
+
+// Markdown returns the conversation as Markdown, with a heading for each message's role and the content below it
+// unmodified, so fenced code blocks and other formatting are preserved. Child conversations created with Fork are
+// rendered as collapsible sections beneath the message they forked from.
+func Markdown(c *conversation.Conversation) string {
+	var b strings.Builder
+	writeMarkdown(&b, c, 0, 2)
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+func writeMarkdown(b *strings.Builder, c *conversation.Conversation, start, level int) {
+	if level > 6 {
+		level = 6
+	}
+	for _, s := range sections(c, start) {
+		for _, m := range s.messages {
+			fmt.Fprintf(b, "%s %s\n\n%s\n\n", strings.Repeat("#", level), heading(m), m.Content())
+		}
+		for _, child := range s.children {
+			fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n", summary(child))
+			writeMarkdown(b, child, shared(child), level+1)
+			b.WriteString("</details>\n\n")
+		}
+	}
+}
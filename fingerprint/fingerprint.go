@@ -0,0 +1,125 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/bradfair/chat/conversation"
+	"io"
+)
+
+// Hash is a SHA-256 fingerprint of a message or conversation.
+type Hash [sha256.Size]byte
+
+// String returns the hash as a hexadecimal string.
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// Domain separators keep message hashes and conversation hashes from ever colliding with each other.
+const (
+	messageDomain      = "chat/message/v1"
+	conversationDomain = "chat/conversation/v1"
+)
+
+// Message returns the canonical hash of a message, computed from its role, content, name and parts. Name and parts are
+// included for messages that provide Name() string and Parts() []string methods, such as message.Message. Tokenizers,
+// timestamps and metadata do not affect the hash.
+func Message(m conversation.Message) Hash {
+	var name string
+	if named, ok := m.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+	var parts []string
+	if multipart, ok := m.(interface{ Parts() []string }); ok {
+		parts = multipart.Parts()
+	}
+	h := sha256.New()
+	write(h, messageDomain)
+	write(h, m.Role())
+	write(h, m.Content())
+	write(h, name)
+	writeLength(h, len(parts))
+	for _, part := range parts {
+		write(h, part)
+	}
+	var sum Hash
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Conversation returns the canonical hash of a conversation's messages. Conversations with the same messages in the
+// same order have the same hash, regardless of their parents or children.
+func Conversation(c *conversation.Conversation) Hash {
+	return Messages(c.Messages())
+}
+
+// Messages returns the canonical hash of a list of messages. The hash is a chain in which each message's hash is
+// combined with the hash of the messages before it, so the hash of any prefix of a list is one of its Prefixes.
+func Messages(messages conversation.Messages) Hash {
+	sum := root()
+	for _, m := range messages {
+		sum = link(sum, Message(m))
+	}
+	return sum
+}
+
+// Prefixes returns the hash of every prefix of the messages, from the empty prefix up to and including all of the
+// messages. The hash at index i is the hash of the first i messages, so the result has one more element than messages.
+func Prefixes(messages conversation.Messages) []Hash {
+	prefixes := make([]Hash, 0, len(messages)+1)
+	sum := root()
+	prefixes = append(prefixes, sum)
+	for _, m := range messages {
+		sum = link(sum, Message(m))
+		prefixes = append(prefixes, sum)
+	}
+	return prefixes
+}
+
+// CommonPrefix returns the number of leading messages two conversations have in common.
+func CommonPrefix(a, b *conversation.Conversation) int {
+	am, bm := a.Messages(), b.Messages()
+	n := 0
+	for n < len(am) && n < len(bm) && Message(am[n]) == Message(bm[n]) {
+		n++
+	}
+	return n
+}
+
+// IsPrefix returns true if every message of prefix appears, in order, at the start of c. A conversation is a prefix of
+// itself.
+func IsPrefix(prefix, c *conversation.Conversation) bool {
+	return CommonPrefix(prefix, c) == len(prefix.Messages())
+}
+
+// Equal returns true if two conversations have the same messages in the same order.
+func Equal(a, b *conversation.Conversation) bool {
+	return Conversation(a) == Conversation(b)
+}
+
+// root returns the hash of an empty list of messages.
+func root() Hash {
+	return sha256.Sum256([]byte(conversationDomain))
+}
+
+// link returns the hash of a list of messages from the hash of all but its last message and the hash of its last
+// message.
+func link(prefix, m Hash) Hash {
+	var b [2 * sha256.Size]byte
+	copy(b[:], prefix[:])
+	copy(b[sha256.Size:], m[:])
+	return sha256.Sum256(b[:])
+}
+
+// write writes a length-prefixed string, so that adjacent fields cannot be confused with one another.
+func write(w io.Writer, s string) {
+	writeLength(w, len(s))
+	_, _ = w.Write([]byte(s))
+}
+
+// writeLength writes a length as an unsigned varint.
+func writeLength(w io.Writer, n int) {
+	var b [binary.MaxVarintLen64]byte
+	_, _ = w.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+}
@@ -0,0 +1,88 @@
+package fingerprint_test
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/fingerprint"
+	"github.com/bradfair/chat/message"
+	"testing"
+	"time"
+)
+
+func TestMessage(t *testing.T) {
+	m := message.New().WithRole("user").WithContent("Hello!")
+	t.Run("stable", func(t *testing.T) {
+		if fingerprint.Message(m) != fingerprint.Message(message.New().WithRole("user").WithContent("Hello!")) {
+			t.Errorf("expected equal messages to have equal hashes")
+		}
+		if len(fingerprint.Message(m).String()) != 64 {
+			t.Errorf("expected a 64 character hex string, got %q", fingerprint.Message(m).String())
+		}
+	})
+	t.Run("ignored fields", func(t *testing.T) {
+		other := m.WithMetadata("id", "1").WithTimestamp(time.Now()).WithTokenizer(message.TokenizerFunc(func(string) ([]int, error) { return nil, nil }))
+		if fingerprint.Message(m) != fingerprint.Message(other) {
+			t.Errorf("expected metadata, timestamps and tokenizers to be ignored")
+		}
+	})
+	t.Run("hashed fields", func(t *testing.T) {
+		for name, other := range map[string]message.Message{
+			"role":    m.WithRole("assistant"),
+			"content": m.WithContent("Hello?"),
+			"name":    m.WithName("alice"),
+			"parts":   message.New().WithRole("user").WithParts("Hello", "!"),
+			"fields":  message.New().WithRole("userHello!"),
+		} {
+			if fingerprint.Message(m) == fingerprint.Message(other) {
+				t.Errorf("expected %s to change the hash", name)
+			}
+		}
+	})
+}
+
+func TestConversation(t *testing.T) {
+	messages := conversation.Messages{
+		message.New().WithRole("system").WithContent("Be brief."),
+		message.New().WithRole("user").WithContent("Hello!"),
+		message.New().WithRole("assistant").WithContent("Hi!"),
+	}
+	c := conversation.New().WithMessages(messages...)
+	t.Run("prefixes share hashes", func(t *testing.T) {
+		prefixes := fingerprint.Prefixes(messages)
+		if len(prefixes) != 4 {
+			t.Fatalf("expected 4 prefixes, got %d", len(prefixes))
+		}
+		for i := range prefixes {
+			if prefixes[i] != fingerprint.Messages(messages[:i]) {
+				t.Errorf("expected prefix %d to match the hash of the first %d messages", i, i)
+			}
+		}
+		if prefixes[3] != fingerprint.Conversation(c) {
+			t.Errorf("expected last prefix to be the conversation hash")
+		}
+		if fingerprint.Messages(nil) == fingerprint.Message(messages[0]) {
+			t.Errorf("expected conversation and message hashes to differ")
+		}
+	})
+	t.Run("order matters", func(t *testing.T) {
+		reversed := conversation.New().WithMessages(messages[2], messages[1], messages[0])
+		if fingerprint.Equal(c, reversed) {
+			t.Errorf("expected conversations in a different order to differ")
+		}
+		if !fingerprint.Equal(c, c.NewChild().WithMessages(messages...)) {
+			t.Errorf("expected conversations with the same messages to be equal")
+		}
+	})
+	t.Run("is prefix", func(t *testing.T) {
+		prefix := conversation.New().WithMessages(messages[:2]...)
+		if !fingerprint.IsPrefix(prefix, c) || !fingerprint.IsPrefix(c, c) || !fingerprint.IsPrefix(conversation.New(), c) {
+			t.Errorf("expected prefix to be detected")
+		}
+		if fingerprint.IsPrefix(c, prefix) {
+			t.Errorf("expected a longer conversation not to be a prefix")
+		}
+		diverged := conversation.New().WithMessages(messages[0], message.New().WithRole("user").WithContent("Bye!"))
+		if fingerprint.IsPrefix(diverged, c) || fingerprint.CommonPrefix(diverged, c) != 1 {
+			t.Errorf("expected conversations to share only their first message")
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package gemini
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+const (
+	// RoleUser is the Gemini role for content from the user.
+	RoleUser = "user"
+	// RoleModel is the Gemini role for content from the model, which corresponds to message.RoleAssistant.
+	RoleModel = "model"
+)
+
+// Provider is the provider name used to look up wire roles in a message.Registry.
+const Provider = "gemini"
+
+// Part is a piece of a content's text.
+type Part struct {
+	Text string `json:"text"`
+}
+
+// Content is a message in the shape used by Gemini-style APIs.
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+// Request is the conversation-related portion of a Gemini-style generateContent request.
+type Request struct {
+	SystemInstruction *Content  `json:"systemInstruction,omitempty"`
+	Contents          []Content `json:"contents"`
+}
+
+// ToRequest converts a conversation into a Gemini-style request.
+//
+// System messages are hoisted into the system instruction, one part per message part. Assistant messages are given the
+// model role, and any role other than system or assistant is given the user role. Every other message becomes its own
+// content, with one part per message part, so messages built with message.WithParts keep their parts.
+//
+// Converting the request back with FromRequest returns the same roles, contents and parts, except for:
+//   - system messages, which come back as a single system message at the start of the conversation, with the parts
+//     of every system message in order, so a conversation with one leading system message round-trips exactly;
+//   - messages with any role other than system, user or assistant, which come back with the user role;
+//   - names, metadata and timestamps, which the request has no place for.
+func ToRequest(c *conversation.Conversation) Request {
+	r, _ := ToRequestWithRegistry(c, nil)
+	return r
+}
+
+// ToRequestWithRegistry converts a conversation like ToRequest, after mapping each message's role to the role it is
+// sent as to the Provider with the registry's Resolve method. Roles mapped to RoleModel are sent as the model. A nil
+// registry leaves roles unchanged, in which case no error is returned.
+func ToRequestWithRegistry(c *conversation.Conversation, registry *message.Registry) (Request, error) {
+	var r Request
+	for _, m := range c.Messages() {
+		wire, err := registry.Resolve(message.Role(m.Role()), Provider)
+		if err != nil {
+			return Request{}, err
+		}
+		switch wire {
+		case string(message.RoleSystem):
+			if r.SystemInstruction == nil {
+				r.SystemInstruction = &Content{}
+			}
+			r.SystemInstruction.Parts = append(r.SystemInstruction.Parts, partsOf(m)...)
+		case string(message.RoleAssistant), RoleModel:
+			r.Contents = append(r.Contents, Content{Role: RoleModel, Parts: partsOf(m)})
+		default:
+			r.Contents = append(r.Contents, Content{Role: RoleUser, Parts: partsOf(m)})
+		}
+	}
+	return r, nil
+}
+
+// FromRequest converts a Gemini-style request into a conversation, giving each message the tokenizer, which may be nil.
+//
+// The system instruction becomes a single system message with its parts at the start of the conversation, and each
+// content becomes a message with its parts. The model role is mapped to message.RoleAssistant, a missing role is mapped to
+// message.RoleUser, and any other role is kept as-is.
+func FromRequest(r Request, tokenizer message.Tokenizer) *conversation.Conversation {
+	var messages []conversation.Message
+	if r.SystemInstruction != nil {
+		system := *r.SystemInstruction
+		system.Role = string(message.RoleSystem)
+		messages = append(messages, FromContent(system).WithTokenizer(tokenizer))
+	}
+	for _, content := range r.Contents {
+		messages = append(messages, FromContent(content).WithTokenizer(tokenizer))
+	}
+	return conversation.New().WithMessages(messages...)
+}
+
+// FromContent converts a single content, such as a candidate in a generateContent response, into a message.
+func FromContent(content Content) message.Message {
+	role := message.Role(content.Role)
+	switch content.Role {
+	case RoleModel:
+		role = message.RoleAssistant
+	case "":
+		role = message.RoleUser
+	}
+	parts := make([]string, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		parts = append(parts, part.Text)
+	}
+	return message.New().WithRole(role).WithParts(parts...)
+}
+
+// partsOf returns the parts of a message, or its content as a single part if it doesn't have any.
+func partsOf(m conversation.Message) []Part {
+	if multi, ok := m.(interface{ Parts() []string }); ok && multi.Parts() != nil {
+		parts := make([]Part, 0, len(multi.Parts()))
+		for _, part := range multi.Parts() {
+			parts = append(parts, Part{Text: part})
+		}
+		return parts
+	}
+	return []Part{{Text: m.Content()}}
+}
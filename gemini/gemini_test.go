@@ -0,0 +1,112 @@
+package gemini_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/gemini"
+	"github.com/bradfair/chat/message"
+	"reflect"
+	"testing"
+)
+
+func TestToRequest(t *testing.T) {
+	c := conversation.New().WithMessages(
+		message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+		message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+		message.New().WithRole(message.RoleAssistant).WithParts("Hi ", "there!"),
+	)
+	b, err := json.Marshal(gemini.ToRequest(c))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `{"systemInstruction":{"parts":[{"text":"Be brief."}]},"contents":[{"role":"user","parts":[{"text":"Hello!"}]},{"role":"model","parts":[{"text":"Hi "},{"text":"there!"}]}]}`
+	if string(b) != want {
+		t.Errorf("expected request to be %s, got %s", want, string(b))
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	var r gemini.Request
+	err := json.Unmarshal([]byte(`{"systemInstruction":{"parts":[{"text":"Be brief."},{"text":"Be kind."}]},"contents":[{"role":"user","parts":[{"text":"Hello!"}]},{"role":"model","parts":[{"text":"Hi "},{"text":"there!"}]},{"parts":[{"text":"How are you?"}]}]}`), &r)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	c := gemini.FromRequest(r, nil)
+	want := "system: Be brief.Be kind.\nuser: Hello!\nassistant: Hi there!\nuser: How are you?"
+	if c.Messages().Transcript() != want {
+		t.Errorf("expected transcript to be %q, got %q", want, c.Messages().Transcript())
+	}
+	t.Run("round trip", func(t *testing.T) {
+		roundTrip := gemini.ToRequest(c)
+		r.Contents[2].Role = gemini.RoleUser
+		if !reflect.DeepEqual(roundTrip, r) {
+			t.Errorf("expected round trip to be %+v, got %+v", r, roundTrip)
+		}
+	})
+	t.Run("tokenizer", func(t *testing.T) {
+		tokenizer := message.TokenizerFunc(func(s string) ([]int, error) { return []int{0}, nil })
+		count, err := gemini.FromRequest(r, tokenizer).CountTokens()
+		if err != nil || count != 4 {
+			t.Errorf("expected 4 tokens and no error, got %d and %v", count, err)
+		}
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Run("exact", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleSystem).WithParts("Be brief. ", "Be kind."),
+			message.New().WithRole(message.RoleUser).WithParts("Hello!"),
+			message.New().WithRole(message.RoleAssistant).WithParts("Hi ", "there!"),
+		)
+		roundTrip := gemini.FromRequest(gemini.ToRequest(c), nil)
+		if roundTrip.Messages().Len() != 3 {
+			t.Fatalf("expected 3 messages, got %d", roundTrip.Messages().Len())
+		}
+		for i, m := range c.Messages() {
+			got, want := message.From(roundTrip.Message(i)), message.From(m)
+			if got.Role() != want.Role() || !reflect.DeepEqual(got.Parts(), want.Parts()) {
+				t.Errorf("expected message %d to be %s: %q, got %s: %q", i, want.Role(), want.Parts(), got.Role(), got.Parts())
+			}
+		}
+	})
+	t.Run("lossy", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleUser).WithContent("Hello!").WithName("alice").WithMetadata("id", "1"),
+			message.New().WithRole(message.RoleSystem).WithParts("Be brief. ", "Be kind."),
+			message.New().WithRole("tool").WithContent("42"),
+			message.New().WithRole(message.RoleSystem).WithContent(" Be honest."),
+		)
+		roundTrip := gemini.FromRequest(gemini.ToRequest(c), nil)
+		want := "system: Be brief. Be kind. Be honest.\nuser: Hello!\nuser: 42"
+		if got := roundTrip.Messages().Transcript(); got != want {
+			t.Errorf("expected transcript to be %q, got %q", want, got)
+		}
+		if parts := message.From(roundTrip.Message(0)).Parts(); len(parts) != 3 {
+			t.Errorf("expected the system parts to be kept in order, got %q", parts)
+		}
+		m := message.From(roundTrip.Message(1))
+		if m.Name() != "" || len(m.Metadata()) != 0 {
+			t.Errorf("expected name and metadata to be dropped, got %q and %v", m.Name(), m.Metadata())
+		}
+	})
+}
+
+func TestToRequestWithRegistry(t *testing.T) {
+	registry := message.NewRegistry()
+	registry.Register("narrator", map[string]string{gemini.Provider: gemini.RoleModel})
+	registry.SetStrict(true)
+	c := conversation.New().WithMessages(
+		message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+		message.New().WithRole("narrator").WithContent("Meanwhile..."),
+	)
+	r, err := gemini.ToRequestWithRegistry(c, registry)
+	if err != nil || len(r.Contents) != 2 || r.Contents[1].Role != gemini.RoleModel {
+		t.Errorf("expected the narrator to be sent as the model, got %+v and %v", r.Contents, err)
+	}
+	c.Append(message.New().WithRole("critic").WithContent("Too long."))
+	if _, err := gemini.ToRequestWithRegistry(c, registry); !errors.Is(err, message.ErrUnregisteredRole) {
+		t.Errorf("expected ErrUnregisteredRole, got %v", err)
+	}
+}
@@ -2,10 +2,13 @@ module github.com/bradfair/chat
 
 go 1.20
 
-require github.com/sashabaranov/go-openai v1.5.7
+require (
+	github.com/fatih/color v1.15.0
+	github.com/sashabaranov/go-openai v1.5.7
+	golang.org/x/text v0.14.0
+)
 
 require (
-	github.com/fatih/color v1.15.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
 	golang.org/x/sys v0.6.0 // indirect
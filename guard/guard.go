@@ -0,0 +1,140 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+// Overflow is a message that doesn't fit in a model's context window.
+type Overflow struct {
+	// Index is the index of the message in the conversation.
+	Index int
+	// Tokens is the number of tokens in the message.
+	Tokens int
+}
+
+// ContextLimitError is returned when a conversation doesn't fit in a model's context window.
+type ContextLimitError struct {
+	// Profile is the model the conversation was checked against.
+	Profile completion.Profile
+	// Tokens is the number of tokens in the conversation.
+	Tokens int
+	// Reserved is the number of tokens reserved for the completion.
+	Reserved int
+	// Overflow is the number of tokens by which the conversation and reserved tokens exceed the context window.
+	Overflow int
+	// Messages are the oldest messages that don't fit once the context window is filled from the most recent message
+	// backwards, i.e. the messages that would have to be removed for the conversation to fit.
+	Messages []Overflow
+}
+
+// Error implements the error interface.
+func (e *ContextLimitError) Error() string {
+	return fmt.Sprintf("conversation of %d tokens plus %d reserved exceeds the %d token context window of %q by %d tokens",
+		e.Tokens, e.Reserved, e.Profile.ContextWindow, e.Profile.Name, e.Overflow)
+}
+
+// Guard checks that conversations fit in a model's context window before they are sent.
+type Guard struct {
+	profile  completion.Profile
+	reserved int
+	policy   Policy
+}
+
+// Check returns the conversation unchanged if it fits in the context window along with the reserved tokens. Otherwise,
+// if a policy is configured, it returns a truncated child of the conversation, and if not, or if the truncated
+// conversation still doesn't fit, it returns a *ContextLimitError. Every message must have a tokenizer.
+func (g Guard) Check(c *conversation.Conversation) (*conversation.Conversation, error) {
+	messages := c.Messages()
+	counts, total, err := count(messages)
+	if err != nil {
+		return nil, err
+	}
+	if g.profile.Fits(total, g.reserved) {
+		return c, nil
+	}
+	if g.policy != nil {
+		truncated := g.policy.Truncate(messages, counts, g.profile.ContextWindow-g.reserved)
+		_, total, err := count(truncated)
+		if err != nil {
+			return nil, err
+		}
+		if g.profile.Fits(total, g.reserved) {
+			return c.NewChild().WithMessages(truncated...), nil
+		}
+	}
+	return nil, g.limitError(counts, total)
+}
+
+// Wrap returns a completer that checks each conversation before calling the given completer, which receives the
+// truncated conversation if the policy truncated it. It can be used as completion.Middleware.
+func (g Guard) Wrap(next completion.Completer) completion.Completer {
+	return completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		checked, err := g.Check(c)
+		if err != nil {
+			return message.Message{}, err
+		}
+		return next.Complete(ctx, checked)
+	})
+}
+
+// limitError describes how a conversation with the given per-message token counts exceeds the context window.
+func (g Guard) limitError(counts []int, total int) *ContextLimitError {
+	e := &ContextLimitError{
+		Profile:  g.profile,
+		Tokens:   total,
+		Reserved: g.reserved,
+		Overflow: total + g.reserved - g.profile.ContextWindow,
+	}
+	budget := g.profile.ContextWindow - g.reserved
+	i := len(counts) - 1
+	for ; i >= 0 && counts[i] <= budget; i-- {
+		budget -= counts[i]
+	}
+	for j := 0; j <= i; j++ {
+		e.Messages = append(e.Messages, Overflow{Index: j, Tokens: counts[j]})
+	}
+	return e
+}
+
+// WithProfile configures a guard with the model to check conversations against.
+func (g Guard) WithProfile(p completion.Profile) Guard {
+	g.profile = p
+	return g
+}
+
+// WithReservedTokens configures a guard with the number of tokens reserved for the completion.
+func (g Guard) WithReservedTokens(n int) Guard {
+	g.reserved = n
+	return g
+}
+
+// WithPolicy configures a guard with the policy used to truncate conversations that don't fit. Without a policy,
+// conversations that don't fit are rejected.
+func (g Guard) WithPolicy(p Policy) Guard {
+	g.policy = p
+	return g
+}
+
+// New creates a new guard.
+func New() Guard {
+	g := Guard{}
+	return g
+}
+
+// count returns the number of tokens in each message and in total.
+func count(messages conversation.Messages) (counts []int, total int, err error) {
+	counts = make([]int, len(messages))
+	for i, m := range messages {
+		tokens, err := m.Tokenize()
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not tokenize message %q: %w", m.Content(), err)
+		}
+		counts[i] = len(tokens)
+		total += len(tokens)
+	}
+	return counts, total, nil
+}
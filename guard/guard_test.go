@@ -0,0 +1,93 @@
+package guard_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/guard"
+	"github.com/bradfair/chat/message"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	profile := completion.Profile{Name: "small", ContextWindow: 10}
+	t.Run("fits", func(t *testing.T) {
+		c := testConversation()
+		checked, err := guard.New().WithProfile(profile).Check(c)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if checked != c {
+			t.Errorf("expected conversation to be returned unchanged")
+		}
+	})
+	t.Run("exceeds", func(t *testing.T) {
+		_, err := guard.New().WithProfile(profile).WithReservedTokens(4).Check(testConversation())
+		var limitErr *guard.ContextLimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("expected a ContextLimitError, got %v", err)
+		}
+		if limitErr.Tokens != 10 || limitErr.Overflow != 4 {
+			t.Errorf("unexpected error %+v", limitErr)
+		}
+		want := []guard.Overflow{{Index: 0, Tokens: 3}, {Index: 1, Tokens: 2}}
+		if !reflect.DeepEqual(limitErr.Messages, want) {
+			t.Errorf("expected offending messages to be %v, got %v", want, limitErr.Messages)
+		}
+	})
+	t.Run("drop oldest", func(t *testing.T) {
+		c := testConversation()
+		checked, err := guard.New().WithProfile(profile).WithReservedTokens(4).WithPolicy(guard.DropOldest).Check(c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if checked.Parent() != c {
+			t.Errorf("expected truncated conversation to be a child of the original")
+		}
+		if checked.Messages().Transcript() != "user: how are you?\nassistant: fine, thanks" {
+			t.Errorf("unexpected transcript %q", checked.Messages().Transcript())
+		}
+	})
+	t.Run("drop oldest keep system", func(t *testing.T) {
+		checked, err := guard.New().WithProfile(profile).WithReservedTokens(4).WithPolicy(guard.DropOldestKeepSystem).Check(testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if checked.Messages().Transcript() != "system: be very brief\nassistant: fine, thanks" {
+			t.Errorf("unexpected transcript %q", checked.Messages().Transcript())
+		}
+	})
+	t.Run("policy cannot fit", func(t *testing.T) {
+		_, err := guard.New().WithProfile(profile).WithReservedTokens(9).WithPolicy(guard.DropOldestKeepSystem).Check(testConversation())
+		var limitErr *guard.ContextLimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("expected a ContextLimitError, got %v", err)
+		}
+	})
+	t.Run("wrap", func(t *testing.T) {
+		called := false
+		next := completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+			called = true
+			return message.Message{}, nil
+		})
+		_, err := guard.New().WithProfile(profile).WithReservedTokens(4).Wrap(next).Complete(context.Background(), testConversation())
+		var limitErr *guard.ContextLimitError
+		if !errors.As(err, &limitErr) || called {
+			t.Errorf("expected a ContextLimitError before any request, got %v", err)
+		}
+	})
+}
+
+// testConversation returns a conversation of 10 tokens.
+func testConversation() *conversation.Conversation {
+	tokenizer := message.TokenizerFunc(func(s string) ([]int, error) { return make([]int, len(strings.Fields(s))), nil })
+	return conversation.New().WithMessages(
+		message.New().WithRole("system").WithContent("be very brief").WithTokenizer(tokenizer),
+		message.New().WithRole("user").WithContent("hello there").WithTokenizer(tokenizer),
+		message.New().WithRole("user").WithContent("how are you?").WithTokenizer(tokenizer),
+		message.New().WithRole("assistant").WithContent("fine, thanks").WithTokenizer(tokenizer),
+	)
+}
@@ -0,0 +1,60 @@
+package guard
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+)
+
+// Policy truncates conversations that don't fit in a model's context window.
+type Policy interface {
+	// Truncate returns the messages to send, given the conversation's messages, the number of tokens in each, and the
+	// number of tokens available for them.
+	Truncate(messages conversation.Messages, counts []int, budget int) conversation.Messages
+}
+
+// PolicyFunc wraps a function as a policy.
+type PolicyFunc func(messages conversation.Messages, counts []int, budget int) conversation.Messages
+
+// Truncate calls the wrapped function.
+func (f PolicyFunc) Truncate(messages conversation.Messages, counts []int, budget int) conversation.Messages {
+	return f(messages, counts, budget)
+}
+
+// DropOldest is a policy that removes the oldest messages until the rest fit.
+var DropOldest Policy = PolicyFunc(func(messages conversation.Messages, counts []int, budget int) conversation.Messages {
+	return dropOldest(messages, counts, budget, func(conversation.Message) bool { return false })
+})
+
+// DropOldestKeepSystem is a policy that removes the oldest messages other than system messages until the rest fit,
+// so that system prompts are kept.
+var DropOldestKeepSystem Policy = PolicyFunc(func(messages conversation.Messages, counts []int, budget int) conversation.Messages {
+	return dropOldest(messages, counts, budget, func(m conversation.Message) bool {
+		return m.Role() == string(message.RoleSystem)
+	})
+})
+
+// dropOldest removes the oldest messages that aren't kept until the total fits in the budget.
+func dropOldest(messages conversation.Messages, counts []int, budget int, keep func(conversation.Message) bool) conversation.Messages {
+	var total int
+	for _, n := range counts {
+		total += n
+	}
+	dropped := make([]bool, len(messages))
+	for i, m := range messages {
+		if total <= budget {
+			break
+		}
+		if keep(m) {
+			continue
+		}
+		dropped[i] = true
+		total -= counts[i]
+	}
+	var result conversation.Messages
+	for i, m := range messages {
+		if !dropped[i] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
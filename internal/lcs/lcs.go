@@ -0,0 +1,90 @@
+// Package lcs aligns two sequences using their longest common subsequence. It is shared by the packages that report
+// differences between lists of messages and between words.
+package lcs
+
+// Op is the kind of a step in an alignment.
+type Op int
+
+const (
+	// Equal marks an element that appears in both sequences.
+	Equal Op = iota
+	// Delete marks an element that only appears in the first sequence.
+	Delete
+	// Insert marks an element that only appears in the second sequence.
+	Insert
+)
+
+// Edit is a single step of an alignment. I is the index in the first sequence, or -1 for insertions, and J is the
+// index in the second sequence, or -1 for deletions.
+type Edit struct {
+	Op   Op
+	I, J int
+}
+
+// MaxCells is the largest table of subsequence lengths Align builds, which takes about 32MB. When the differing
+// middle of two sequences would need a larger table, it is reported as deleted and inserted as a whole instead.
+const MaxCells = 1 << 22
+
+// Align aligns two sequences of lengths n and m, returning the steps that turn the first into the second. Elements
+// the sequences start or end with are matched directly, and the rest are aligned by their longest common subsequence.
+// Where an element could either be deleted or inserted next, the deletion comes first.
+func Align(n, m int, equal func(i, j int) bool) []Edit {
+	var edits []Edit
+	start := 0
+	for ; start < n && start < m && equal(start, start); start++ {
+		edits = append(edits, Edit{Op: Equal, I: start, J: start})
+	}
+	end := 0
+	for end < n-start && end < m-start && equal(n-1-end, m-1-end) {
+		end++
+	}
+	edits = append(edits, middle(start, n-end, start, m-end, equal)...)
+	for k := end; k > 0; k-- {
+		edits = append(edits, Edit{Op: Equal, I: n - k, J: m - k})
+	}
+	return edits
+}
+
+// middle aligns the elements from i0 up to i1 of the first sequence with those from j0 up to j1 of the second.
+func middle(i0, i1, j0, j1 int, equal func(i, j int) bool) []Edit {
+	n, m := i1-i0, j1-j0
+	var edits []Edit
+	if (n+1)*(m+1) > MaxCells {
+		for i := i0; i < i1; i++ {
+			edits = append(edits, Edit{Op: Delete, I: i, J: -1})
+		}
+		for j := j0; j < j1; j++ {
+			edits = append(edits, Edit{Op: Insert, I: -1, J: j})
+		}
+		return edits
+	}
+	width := m + 1
+	lengths := make([]int, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(i0+i, j0+j) {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else if down, right := lengths[(i+1)*width+j], lengths[i*width+j+1]; down >= right {
+				lengths[i*width+j] = down
+			} else {
+				lengths[i*width+j] = right
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && equal(i0+i, j0+j):
+			edits = append(edits, Edit{Op: Equal, I: i0 + i, J: j0 + j})
+			i++
+			j++
+		case j >= m || (i < n && lengths[(i+1)*width+j] >= lengths[i*width+j+1]):
+			edits = append(edits, Edit{Op: Delete, I: i0 + i, J: -1})
+			i++
+		default:
+			edits = append(edits, Edit{Op: Insert, I: -1, J: j0 + j})
+			j++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,42 @@
+package lcs_test
+
+import (
+	"github.com/bradfair/chat/internal/lcs"
+	"reflect"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	align := func(a, b string) []lcs.Edit {
+		return lcs.Align(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+	}
+	t.Run("subsequence", func(t *testing.T) {
+		want := []lcs.Edit{
+			{Op: lcs.Equal, I: 0, J: 0},
+			{Op: lcs.Delete, I: 1, J: -1},
+			{Op: lcs.Insert, I: -1, J: 1},
+			{Op: lcs.Equal, I: 2, J: 2},
+			{Op: lcs.Insert, I: -1, J: 3},
+			{Op: lcs.Equal, I: 3, J: 4},
+		}
+		if got := align("abcd", "axcyd"); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		if got := align("", ""); len(got) != 0 {
+			t.Errorf("expected no edits, got %+v", got)
+		}
+		want := []lcs.Edit{{Op: lcs.Insert, I: -1, J: 0}}
+		if got := align("", "a"); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+	t.Run("too large", func(t *testing.T) {
+		n := 3000
+		edits := lcs.Align(n, n, func(i, j int) bool { return i == 0 && j == 0 })
+		if len(edits) != 2*n-1 || edits[0].Op != lcs.Equal || edits[1].Op != lcs.Delete || edits[n].Op != lcs.Insert {
+			t.Errorf("expected the middle to be replaced as a whole, got %d edits", len(edits))
+		}
+	})
+}
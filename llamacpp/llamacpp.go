@@ -0,0 +1,192 @@
+package llamacpp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/render"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of a local llama.cpp server.
+const DefaultBaseURL = "http://localhost:8080"
+
+// Request is the body of a /completion request.
+type Request struct {
+	Prompt      string   `json:"prompt"`
+	NPredict    int      `json:"n_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Stream      bool     `json:"stream"`
+}
+
+// Response is the body of a /completion response, or a single event of a streamed response.
+type Response struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	Model           string `json:"model"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+	TokensPredicted int    `json:"tokens_predicted"`
+}
+
+// Client is a completer that renders conversations into a prompt and sends them to a llama.cpp server's /completion
+// endpoint.
+type Client struct {
+	baseURL     string
+	renderer    render.Renderer
+	nPredict    int
+	temperature *float64
+	stop        []string
+	httpClient  *http.Client
+	tokenizer   message.Tokenizer
+}
+
+// NewRequest returns the request the client sends for a conversation.
+func (c Client) NewRequest(convo *conversation.Conversation, stream bool) Request {
+	return Request{
+		Prompt:      c.renderer.Render(convo),
+		NPredict:    c.nPredict,
+		Temperature: c.temperature,
+		Stop:        c.stop,
+		Stream:      stream,
+	}
+}
+
+// Complete implements the completion.Completer interface.
+func (c Client) Complete(ctx context.Context, convo *conversation.Conversation) (message.Message, error) {
+	return c.send(ctx, convo, false, nil)
+}
+
+// Stream implements the completion.Streamer interface.
+func (c Client) Stream(ctx context.Context, convo *conversation.Conversation, fn func(chunk string) error) (message.Message, error) {
+	return c.send(ctx, convo, true, fn)
+}
+
+// send sends a request and reads the response, which is a single JSON object, or a series of server-sent events when
+// streaming.
+func (c Client) send(ctx context.Context, convo *conversation.Conversation, stream bool, fn func(chunk string) error) (message.Message, error) {
+	body, err := json.Marshal(c.NewRequest(convo, stream))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.baseURL, "/")+"/completion", bytes.NewReader(body))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(resp.Body)
+		return message.Message{}, completion.NewStatusError(resp, b)
+	}
+
+	var content strings.Builder
+	var last Response
+	if !stream {
+		if err := json.NewDecoder(resp.Body).Decode(&last); err != nil {
+			return message.Message{}, fmt.Errorf("could not decode response: %w", err)
+		}
+		content.WriteString(last.Content)
+	} else {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event Response
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return message.Message{}, fmt.Errorf("could not decode event: %w", err)
+			}
+			content.WriteString(event.Content)
+			if fn != nil && event.Content != "" {
+				if err := fn(event.Content); err != nil {
+					return message.Message{}, err
+				}
+			}
+			last = event
+			if event.Stop {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return message.Message{}, fmt.Errorf("could not read response: %w", err)
+		}
+		if !last.Stop {
+			return message.Message{}, fmt.Errorf("response ended before it stopped: %w", io.ErrUnexpectedEOF)
+		}
+	}
+
+	m := message.New().
+		WithRole(message.RoleAssistant).
+		WithContent(content.String()).
+		WithTokenizer(c.tokenizer).
+		WithMetadata(completion.MetadataModel, last.Model)
+	return completion.WithUsage(m, completion.Usage{PromptTokens: last.TokensEvaluated, CompletionTokens: last.TokensPredicted}), nil
+}
+
+// WithBaseURL configures a client with the base URL of the server.
+func (c Client) WithBaseURL(url string) Client {
+	c.baseURL = url
+	return c
+}
+
+// WithRenderer configures a client with the renderer used to build prompts. The renderer should match the chat
+// template the model was trained with. The default is render.ChatML.
+func (c Client) WithRenderer(r render.Renderer) Client {
+	c.renderer = r
+	return c
+}
+
+// WithMaxTokens configures a client with the maximum number of tokens to generate.
+func (c Client) WithMaxTokens(n int) Client {
+	c.nPredict = n
+	return c
+}
+
+// WithTemperature configures a client with the sampling temperature.
+func (c Client) WithTemperature(t float64) Client {
+	c.temperature = &t
+	return c
+}
+
+// WithStop configures a client with the sequences that stop generation, such as "<|im_end|>" for ChatML.
+func (c Client) WithStop(stop ...string) Client {
+	c.stop = stop
+	return c
+}
+
+// WithHTTPClient configures a client with the HTTP client used to send requests.
+func (c Client) WithHTTPClient(client *http.Client) Client {
+	c.httpClient = client
+	return c
+}
+
+// WithTokenizer configures a client with the tokenizer given to response messages.
+func (c Client) WithTokenizer(t message.Tokenizer) Client {
+	c.tokenizer = t
+	return c
+}
+
+// New creates a new client using the default base URL, the ChatML renderer, and the default HTTP client.
+func New() Client {
+	c := Client{
+		baseURL:    DefaultBaseURL,
+		renderer:   render.ChatML{},
+		stop:       []string{"<|im_end|>"},
+		httpClient: http.DefaultClient,
+	}
+	return c
+}
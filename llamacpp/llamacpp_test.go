@@ -0,0 +1,90 @@
+package llamacpp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/llamacpp"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/render"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	c := conversation.New().WithMessages(
+		message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+		message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+	)
+	t.Run("complete", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/completion" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			var request llamacpp.Request
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("could not decode request: %v", err)
+			}
+			want := "<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHello! [/INST]"
+			if request.Prompt != want || request.Stream || request.NPredict != 64 || !reflect.DeepEqual(request.Stop, []string{"</s>"}) {
+				t.Errorf("unexpected request %+v", request)
+			}
+			_, _ = w.Write([]byte(`{"content":"Hi there!","stop":true,"model":"llama-2-7b-chat","tokens_evaluated":20,"tokens_predicted":4}`))
+		}))
+		defer server.Close()
+		client := llamacpp.New().WithBaseURL(server.URL).WithRenderer(render.Llama2{}).WithStop("</s>").WithMaxTokens(64)
+		m, err := client.Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Role() != "assistant" || m.Content() != "Hi there!" {
+			t.Errorf("unexpected message %s: %s", m.Role(), m.Content())
+		}
+		if usage := completion.UsageOf(m); usage != (completion.Usage{PromptTokens: 20, CompletionTokens: 4}) {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+		if m.Metadata()[completion.MetadataModel] != "llama-2-7b-chat" {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+	})
+	t.Run("stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"content\":\"Hi\",\"stop\":false}\n\ndata: {\"content\":\" there!\",\"stop\":false}\n\ndata: {\"content\":\"\",\"stop\":true,\"tokens_evaluated\":20,\"tokens_predicted\":2}\n\n"))
+		}))
+		defer server.Close()
+		var chunks []string
+		m, err := llamacpp.New().WithBaseURL(server.URL).Stream(context.Background(), c, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(chunks, []string{"Hi", " there!"}) {
+			t.Errorf("unexpected chunks %q", chunks)
+		}
+		if m.Content() != "Hi there!" {
+			t.Errorf("unexpected content %q", m.Content())
+		}
+		if usage := completion.UsageOf(m); usage.TotalTokens() != 22 {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+	})
+	t.Run("stream ends early", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"content\":\"Hi\",\"stop\":false}\n\n"))
+		}))
+		defer server.Close()
+		_, err := llamacpp.New().WithBaseURL(server.URL).Stream(context.Background(), c, func(string) error { return nil })
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+		}
+	})
+}
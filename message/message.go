@@ -2,13 +2,19 @@ package message
 
 import (
 	"encoding/json"
+	"strings"
+	"time"
 )
 
 // Message is a piece of content sent from a role.
 type Message struct {
 	role      Role
 	content   string
+	parts     []string
+	name      string
+	timestamp time.Time
 	tokenizer Tokenizer
+	metadata  map[string]string
 }
 
 // Role returns the name of the role that sent the message.
@@ -21,6 +27,34 @@ func (m Message) Content() string {
 	return m.content
 }
 
+// Parts returns a copy of the parts the message's content was built from with WithParts, or nil if the content was
+// set with WithContent.
+func (m Message) Parts() []string {
+	if m.parts == nil {
+		return nil
+	}
+	return append([]string(nil), m.parts...)
+}
+
+// Name returns the name of the participant that sent the message, if any.
+func (m Message) Name() string {
+	return m.name
+}
+
+// Timestamp returns the time the message was sent. The zero time is returned if no timestamp is set.
+func (m Message) Timestamp() time.Time {
+	return m.timestamp
+}
+
+// Metadata returns a copy of the message's metadata.
+func (m Message) Metadata() map[string]string {
+	metadata := make(map[string]string, len(m.metadata))
+	for k, v := range m.metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
 // IsEmpty returns true if the message is empty.
 func (m Message) IsEmpty() bool {
 	return m.role == "" && m.content == ""
@@ -38,11 +72,15 @@ func (m Message) Tokenize() ([]int, error) {
 // MarshalJSON implements the json.Marshaler interface.
 func (m Message) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
+		Role     string            `json:"role"`
+		Content  string            `json:"content"`
+		Name     string            `json:"name,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
 	}{
-		Role:    m.Role(),
-		Content: m.Content(),
+		Role:     m.Role(),
+		Content:  m.Content(),
+		Name:     m.Name(),
+		Metadata: m.metadata,
 	})
 }
 
@@ -55,6 +93,27 @@ func (m Message) WithRole(role Role) Message {
 // WithContent configures a message with content.
 func (m Message) WithContent(content string) Message {
 	m.content = content
+	m.parts = nil
+	return m
+}
+
+// WithParts configures a message with content made of several parts, such as those of a multi-part API response.
+// The message's content is the concatenation of the parts.
+func (m Message) WithParts(parts ...string) Message {
+	m.content = strings.Join(parts, "")
+	m.parts = append([]string{}, parts...)
+	return m
+}
+
+// WithName configures a message with the name of the participant that sent it.
+func (m Message) WithName(name string) Message {
+	m.name = name
+	return m
+}
+
+// WithTimestamp configures a message with the time it was sent.
+func (m Message) WithTimestamp(t time.Time) Message {
+	m.timestamp = t
 	return m
 }
 
@@ -64,6 +123,54 @@ func (m Message) WithTokenizer(t Tokenizer) Message {
 	return m
 }
 
+// WithMetadata configures a message with a metadata key/value pair. The metadata of the original message is not modified.
+func (m Message) WithMetadata(key, value string) Message {
+	metadata := m.Metadata()
+	metadata[key] = value
+	m.metadata = metadata
+	return m
+}
+
+// WithoutMetadata configures a message without the given metadata keys, or without any metadata if no keys are given.
+// The metadata of the original message is not modified.
+func (m Message) WithoutMetadata(keys ...string) Message {
+	if len(keys) == 0 {
+		m.metadata = nil
+		return m
+	}
+	metadata := m.Metadata()
+	for _, key := range keys {
+		delete(metadata, key)
+	}
+	m.metadata = metadata
+	return m
+}
+
+// From returns m as a Message. If m is already a Message, it is returned unchanged. Otherwise a new message is created
+// with m's role and content, along with its name, timestamp and metadata if m provides Name, Timestamp or Metadata
+// methods like those of Message. The new message has no tokenizer.
+func From(m interface {
+	Role() string
+	Content() string
+}) Message {
+	if msg, ok := m.(Message); ok {
+		return msg
+	}
+	msg := New().WithRole(Role(m.Role())).WithContent(m.Content())
+	if named, ok := m.(interface{ Name() string }); ok {
+		msg = msg.WithName(named.Name())
+	}
+	if timestamped, ok := m.(interface{ Timestamp() time.Time }); ok {
+		msg = msg.WithTimestamp(timestamped.Timestamp())
+	}
+	if md, ok := m.(interface{ Metadata() map[string]string }); ok {
+		for k, v := range md.Metadata() {
+			msg = msg.WithMetadata(k, v)
+		}
+	}
+	return msg
+}
+
 // New creates a new message.
 func New() Message {
 	m := Message{}
@@ -5,6 +5,7 @@ import (
 	"github.com/bradfair/chat/message"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMessage(t *testing.T) {
@@ -36,6 +37,72 @@ func TestMessage(t *testing.T) {
 			t.Errorf("expected json to be {\"role\":\"user\",\"content\":\"hello\"}, got %s", string(b))
 		}
 	})
+	t.Run("parts", func(t *testing.T) {
+		msg := message.New().WithRole("user").WithParts("hello", " world")
+		if msg.Content() != "hello world" {
+			t.Errorf("expected content to be hello world, got %s", msg.Content())
+		}
+		if parts := msg.Parts(); len(parts) != 2 || parts[0] != "hello" || parts[1] != " world" {
+			t.Errorf("unexpected parts %q", parts)
+		}
+		if msg.WithContent("hello").Parts() != nil {
+			t.Errorf("expected WithContent to clear parts")
+		}
+	})
+	t.Run("name and timestamp", func(t *testing.T) {
+		sent := time.Date(2023, 5, 26, 12, 0, 0, 0, time.UTC)
+		msg := message.New().WithRole("user").WithContent("hello").WithName("alice").WithTimestamp(sent)
+		if msg.Name() != "alice" {
+			t.Errorf("expected name to be alice, got %s", msg.Name())
+		}
+		if !msg.Timestamp().Equal(sent) {
+			t.Errorf("expected timestamp to be %v, got %v", sent, msg.Timestamp())
+		}
+		b, err := msg.MarshalJSON()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if string(b) != `{"role":"user","content":"hello","name":"alice"}` {
+			t.Errorf("unexpected json: %s", string(b))
+		}
+	})
+	t.Run("metadata", func(t *testing.T) {
+		original := message.New().WithRole("user").WithContent("hello").WithMetadata("source", "test")
+		modified := original.WithMetadata("source", "modified")
+		if original.Metadata()["source"] != "test" {
+			t.Errorf("expected original metadata to be unchanged, got %q", original.Metadata()["source"])
+		}
+		if modified.Metadata()["source"] != "modified" {
+			t.Errorf("expected modified metadata to be modified, got %q", modified.Metadata()["source"])
+		}
+		b, err := original.MarshalJSON()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if string(b) != `{"role":"user","content":"hello","metadata":{"source":"test"}}` {
+			t.Errorf("unexpected json: %s", string(b))
+		}
+		tagged := original.WithMetadata("id", "1")
+		if md := tagged.WithoutMetadata("source").Metadata(); len(md) != 1 || md["id"] != "1" {
+			t.Errorf("expected only the id key to remain, got %v", md)
+		}
+		if md := tagged.WithoutMetadata().Metadata(); len(md) != 0 {
+			t.Errorf("expected no metadata, got %v", md)
+		}
+		if len(tagged.Metadata()) != 2 {
+			t.Errorf("expected original metadata to be unchanged, got %v", tagged.Metadata())
+		}
+	})
+	t.Run("from", func(t *testing.T) {
+		original := message.New().WithRole("user").WithContent("hello").WithTokenizer(message.TokenizerFunc(testTokenizer))
+		if _, err := message.From(original).Tokenize(); err != nil {
+			t.Errorf("expected messages to be returned unchanged, got %v", err)
+		}
+		converted := message.From(otherMessage{})
+		if converted.Role() != "user" || converted.Content() != "hi" || converted.Name() != "alice" {
+			t.Errorf("unexpected message %s (%s): %s", converted.Role(), converted.Name(), converted.Content())
+		}
+	})
 	t.Run("tokenize", func(t *testing.T) {
 		t.Run("no tokenizer", func(t *testing.T) {
 			msg := message.New().WithRole("user").WithContent("hello")
@@ -72,3 +139,9 @@ func testTokenizer(content string) (tokens []int, err error) {
 	}
 	return
 }
+
+type otherMessage struct{}
+
+func (otherMessage) Role() string    { return "user" }
+func (otherMessage) Content() string { return "hi" }
+func (otherMessage) Name() string    { return "alice" }
@@ -0,0 +1,132 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnregisteredRole is returned when a role has not been registered.
+var ErrUnregisteredRole = errors.New("unregistered role")
+
+// Registry is a set of roles an application uses, along with the role each is sent as to each provider.
+// It is safe for concurrent use. The zero value is an empty, non-strict registry ready to use.
+//
+// A registry only checks the roles passed to its own methods. Messages created with New().WithRole accept any role
+// whether or not it is registered. Provider adapters configured with a registry, such as anthropic.Client.WithRegistry,
+// map each role with Resolve on the way out, which rejects unregistered roles in strict mode.
+type Registry struct {
+	roles  map[Role]map[string]string
+	strict bool
+	mutex  sync.RWMutex
+}
+
+// Register adds a role to the registry, along with the wire role it is sent as to each provider, keyed by provider
+// name. Registering a role again replaces its mappings.
+func (r *Registry) Register(role Role, wire map[string]string) {
+	mappings := make(map[string]string, len(wire))
+	for provider, w := range wire {
+		mappings[provider] = w
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.init()
+	r.roles[role] = mappings
+}
+
+// IsRegistered returns true if the role has been registered.
+func (r *Registry) IsRegistered(role Role) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.roles[role]
+	return ok
+}
+
+// Roles returns the registered roles in sorted order.
+func (r *Registry) Roles() []Role {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	roles := make([]Role, 0, len(r.roles))
+	for role := range r.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// Wire returns the role a message from the given role is sent as to a provider. Registered roles without a mapping
+// for the provider are sent as themselves. An error wrapping ErrUnregisteredRole is returned for unregistered roles.
+func (r *Registry) Wire(role Role, provider string) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	mappings, ok := r.roles[role]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnregisteredRole, role)
+	}
+	if wire, ok := mappings[provider]; ok {
+		return wire, nil
+	}
+	return string(role), nil
+}
+
+// Resolve returns the role a message from the given role is sent as to a provider, for use by provider adapters.
+// Registered roles are mapped like Wire. Unregistered roles are sent as themselves, unless the registry is strict, in
+// which case an error wrapping ErrUnregisteredRole is returned. A nil registry sends every role as itself.
+func (r *Registry) Resolve(role Role, provider string) (string, error) {
+	if r == nil {
+		return string(role), nil
+	}
+	if err := r.Validate(role); err != nil {
+		return "", err
+	}
+	if wire, err := r.Wire(role, provider); err == nil {
+		return wire, nil
+	}
+	return string(role), nil
+}
+
+// Validate returns an error wrapping ErrUnregisteredRole if the registry is strict and the role has not been
+// registered.
+func (r *Registry) Validate(role Role) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if _, ok := r.roles[role]; r.strict && !ok {
+		return fmt.Errorf("%w %q", ErrUnregisteredRole, role)
+	}
+	return nil
+}
+
+// New creates a new message from the given role. If the registry is strict, an error wrapping ErrUnregisteredRole is
+// returned for unregistered roles.
+func (r *Registry) New(role Role) (Message, error) {
+	if err := r.Validate(role); err != nil {
+		return Message{}, err
+	}
+	return New().WithRole(role), nil
+}
+
+// SetStrict configures whether the registry rejects unregistered roles in New, Validate and Resolve. It has no effect on
+// messages created without the registry.
+func (r *Registry) SetStrict(strict bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.strict = strict
+}
+
+// init initializes the registry.
+func (r *Registry) init() {
+	if r.roles == nil {
+		r.roles = map[Role]map[string]string{}
+	}
+}
+
+// NewRegistry creates a new registry with the system, user and assistant roles registered. The assistant role is
+// mapped to "model" for the "gemini" provider.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.Register(RoleSystem, nil)
+	r.Register(RoleUser, nil)
+	r.Register(RoleAssistant, map[string]string{"gemini": "model"})
+	return r
+}
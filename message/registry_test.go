@@ -0,0 +1,90 @@
+package message_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/message"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("built-in roles", func(t *testing.T) {
+		r := message.NewRegistry()
+		want := []message.Role{message.RoleAssistant, message.RoleSystem, message.RoleUser}
+		if !reflect.DeepEqual(r.Roles(), want) {
+			t.Errorf("expected roles to be %v, got %v", want, r.Roles())
+		}
+		if wire, err := r.Wire(message.RoleAssistant, "gemini"); err != nil || wire != "model" {
+			t.Errorf("expected assistant to be sent to gemini as model, got %q, %v", wire, err)
+		}
+		if wire, err := r.Wire(message.RoleAssistant, "openai"); err != nil || wire != "assistant" {
+			t.Errorf("expected assistant to be sent to openai as assistant, got %q, %v", wire, err)
+		}
+	})
+	t.Run("custom roles", func(t *testing.T) {
+		r := message.NewRegistry()
+		r.Register("critic", map[string]string{"openai": "user", "anthropic": "user"})
+		if !r.IsRegistered("critic") {
+			t.Errorf("expected critic to be registered")
+		}
+		if wire, err := r.Wire("critic", "anthropic"); err != nil || wire != "user" {
+			t.Errorf("expected critic to be sent to anthropic as user, got %q, %v", wire, err)
+		}
+		if _, err := r.Wire("narrator", "openai"); !errors.Is(err, message.ErrUnregisteredRole) {
+			t.Errorf("expected ErrUnregisteredRole, got %v", err)
+		}
+	})
+	t.Run("strict", func(t *testing.T) {
+		r := message.NewRegistry()
+		if _, err := r.New("narrator"); err != nil {
+			t.Errorf("expected unregistered roles to be allowed by default, got %v", err)
+		}
+		r.SetStrict(true)
+		if _, err := r.New("narrator"); !errors.Is(err, message.ErrUnregisteredRole) {
+			t.Errorf("expected ErrUnregisteredRole, got %v", err)
+		}
+		r.Register("narrator", nil)
+		m, err := r.New("narrator")
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if m.Role() != "narrator" {
+			t.Errorf("expected role to be narrator, got %s", m.Role())
+		}
+	})
+	t.Run("zero value", func(t *testing.T) {
+		var r message.Registry
+		if r.IsRegistered(message.RoleUser) || len(r.Roles()) != 0 {
+			t.Errorf("expected an empty registry")
+		}
+		r.Register("narrator", map[string]string{"openai": "user"})
+		if wire, err := r.Wire("narrator", "openai"); err != nil || wire != "user" {
+			t.Errorf("expected narrator to be sent as user, got %q and %v", wire, err)
+		}
+	})
+	t.Run("resolve", func(t *testing.T) {
+		r := message.NewRegistry()
+		r.Register("narrator", map[string]string{"gemini": "model"})
+		for _, tc := range []struct {
+			role     message.Role
+			provider string
+			want     string
+		}{
+			{"narrator", "gemini", "model"},
+			{"narrator", "ollama", "narrator"},
+			{"critic", "gemini", "critic"},
+		} {
+			if got, err := r.Resolve(tc.role, tc.provider); err != nil || got != tc.want {
+				t.Errorf("expected %s to be sent to %s as %s, got %q and %v", tc.role, tc.provider, tc.want, got, err)
+			}
+		}
+		r.SetStrict(true)
+		if _, err := r.Resolve("critic", "gemini"); !errors.Is(err, message.ErrUnregisteredRole) {
+			t.Errorf("expected ErrUnregisteredRole, got %v", err)
+		}
+		var none *message.Registry
+		if got, err := none.Resolve("critic", "gemini"); err != nil || got != "critic" {
+			t.Errorf("expected a nil registry to send roles as themselves, got %q and %v", got, err)
+		}
+	})
+}
@@ -17,3 +17,27 @@ type TokenizerFunc func(string) ([]int, error)
 func (f TokenizerFunc) Tokenize(s string) ([]int, error) {
 	return f(s)
 }
+
+// Detokenizer converts tokens back into text. Tokenizers that also implement Detokenizer can be used to truncate and
+// split messages at token boundaries.
+type Detokenizer interface {
+	// Detokenize joins the given tokens back into a string.
+	Detokenize([]int) (string, error)
+}
+
+// ErrNoDetokenizer is returned when a message's tokenizer does not implement Detokenizer.
+var ErrNoDetokenizer = errors.New("tokenizer cannot detokenize")
+
+// DetokenizerFunc wraps a function as a detokenizer.
+type DetokenizerFunc func([]int) (string, error)
+
+// Detokenize calls the wrapped function.
+func (f DetokenizerFunc) Detokenize(tokens []int) (string, error) {
+	return f(tokens)
+}
+
+// Codec combines a tokenizer and a detokenizer for the same vocabulary into a single tokenizer that can do both.
+type Codec struct {
+	Tokenizer
+	Detokenizer
+}
@@ -0,0 +1,115 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Truncation selects which tokens of a message are kept when it is truncated.
+type Truncation int
+
+const (
+	// KeepHead keeps the first tokens of a message, removing its end.
+	KeepHead Truncation = iota
+	// KeepTail keeps the last tokens of a message, removing its beginning.
+	KeepTail
+	// KeepHeadAndTail keeps the first and last tokens of a message, removing its middle.
+	KeepHeadAndTail
+)
+
+// Truncate returns a copy of the message with its content shortened to at most n tokens. The ellipsis, if any, marks
+// where content was removed and counts toward the n tokens. Messages that already fit are returned unchanged.
+// The message's tokenizer must implement Detokenizer, otherwise ErrNoDetokenizer is returned.
+func (m Message) Truncate(n int, keep Truncation, ellipsis string) (Message, error) {
+	tokens, detokenizer, err := m.codec()
+	if err != nil {
+		return m, err
+	}
+	if len(tokens) <= n {
+		return m, nil
+	}
+	var marker []int
+	if ellipsis != "" {
+		marker, err = m.tokenizer.Tokenize(ellipsis)
+		if err != nil {
+			return m, fmt.Errorf("could not tokenize ellipsis: %w", err)
+		}
+	}
+	budget := n - len(marker)
+	if budget < 0 {
+		budget = 0
+		marker = nil
+	}
+	var truncated []int
+	switch keep {
+	case KeepHead:
+		truncated = join(tokens[:budget], marker)
+	case KeepTail:
+		truncated = join(marker, tokens[len(tokens)-budget:])
+	case KeepHeadAndTail:
+		head := (budget + 1) / 2
+		truncated = join(tokens[:head], marker, tokens[len(tokens)-(budget-head):])
+	default:
+		return m, fmt.Errorf("unknown truncation %d", keep)
+	}
+	content, err := detokenizer.Detokenize(truncated)
+	if err != nil {
+		return m, fmt.Errorf("could not detokenize message: %w", err)
+	}
+	return m.WithContent(content), nil
+}
+
+// Split returns copies of the message whose content is split into consecutive chunks of at most n tokens each.
+// Each copy keeps the role, name, timestamp, tokenizer and metadata of the original. A message that already fits is
+// returned as the only element. The message's tokenizer must implement Detokenizer, otherwise ErrNoDetokenizer is
+// returned.
+func (m Message) Split(n int) ([]Message, error) {
+	if n <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	tokens, detokenizer, err := m.codec()
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) <= n {
+		return []Message{m}, nil
+	}
+	chunks := make([]Message, 0, (len(tokens)+n-1)/n)
+	for start := 0; start < len(tokens); start += n {
+		end := start + n
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		content, err := detokenizer.Detokenize(tokens[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("could not detokenize message: %w", err)
+		}
+		chunks = append(chunks, m.WithContent(content))
+	}
+	return chunks, nil
+}
+
+// codec returns the message's tokens along with its tokenizer as a Detokenizer.
+func (m Message) codec() ([]int, Detokenizer, error) {
+	if m.tokenizer == nil {
+		return nil, nil, ErrNoTokenizer
+	}
+	detokenizer, ok := m.tokenizer.(Detokenizer)
+	if codec, isCodec := m.tokenizer.(Codec); !ok || (isCodec && codec.Detokenizer == nil) {
+		return nil, nil, ErrNoDetokenizer
+	}
+	tokens, err := m.Tokenize()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not tokenize message: %w", err)
+	}
+	return tokens, detokenizer, nil
+}
+
+// join concatenates slices of tokens into a new slice.
+func join(slices ...[]int) []int {
+	var joined []int
+	for _, s := range slices {
+		joined = append(joined, s...)
+	}
+	return joined
+}
@@ -0,0 +1,104 @@
+package message_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/message"
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	m := message.New().WithRole("user").WithContent("one two three four five six seven eight").WithTokenizer(testCodec())
+	tests := []struct {
+		name     string
+		n        int
+		keep     message.Truncation
+		ellipsis string
+		want     string
+	}{
+		{name: "head", n: 3, keep: message.KeepHead, want: "one two three"},
+		{name: "head with ellipsis", n: 3, keep: message.KeepHead, ellipsis: "...", want: "one two ..."},
+		{name: "tail with ellipsis", n: 3, keep: message.KeepTail, ellipsis: "...", want: "... seven eight"},
+		{name: "middle with ellipsis", n: 4, keep: message.KeepHeadAndTail, ellipsis: "...", want: "one two ... eight"},
+		{name: "budget smaller than ellipsis", n: 0, keep: message.KeepHead, ellipsis: "...", want: ""},
+		{name: "fits", n: 8, keep: message.KeepHead, ellipsis: "...", want: "one two three four five six seven eight"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, err := m.Truncate(tt.n, tt.keep, tt.ellipsis)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if truncated.Content() != tt.want {
+				t.Errorf("expected content to be %q, got %q", tt.want, truncated.Content())
+			}
+			if tokens, _ := truncated.Tokenize(); len(tokens) > tt.n && tt.n < 8 {
+				t.Errorf("expected at most %d tokens, got %d", tt.n, len(tokens))
+			}
+		})
+	}
+	t.Run("no detokenizer", func(t *testing.T) {
+		plain := m.WithTokenizer(message.TokenizerFunc(testTokenizer))
+		if _, err := plain.Truncate(2, message.KeepHead, ""); !errors.Is(err, message.ErrNoDetokenizer) {
+			t.Errorf("expected ErrNoDetokenizer, got %v", err)
+		}
+		if _, err := m.WithTokenizer(message.Codec{Tokenizer: message.TokenizerFunc(testTokenizer)}).Split(2); !errors.Is(err, message.ErrNoDetokenizer) {
+			t.Errorf("expected ErrNoDetokenizer, got %v", err)
+		}
+		if _, err := message.New().WithContent("hi").Truncate(2, message.KeepHead, ""); !errors.Is(err, message.ErrNoTokenizer) {
+			t.Errorf("expected ErrNoTokenizer, got %v", err)
+		}
+	})
+}
+
+func TestSplit(t *testing.T) {
+	m := message.New().WithRole("user").WithContent("one two three four five").WithMetadata("source", "paste").WithTokenizer(testCodec())
+	chunks, err := m.Split(2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var contents []string
+	for _, c := range chunks {
+		contents = append(contents, c.Content())
+		if c.Role() != "user" || c.Metadata()["source"] != "paste" {
+			t.Errorf("expected chunks to keep the role and metadata of the original")
+		}
+	}
+	if got := strings.Join(contents, "|"); got != "one two|three four|five" {
+		t.Errorf("unexpected chunks %q", got)
+	}
+	if chunks, _ := m.Split(10); len(chunks) != 1 || chunks[0].Content() != m.Content() {
+		t.Errorf("expected a message that fits to be returned unchanged")
+	}
+	if _, err := m.Split(0); err == nil {
+		t.Errorf("expected an error for a chunk size of 0")
+	}
+}
+
+// testCodec returns a tokenizer that treats each space-separated word as a token, along with a matching detokenizer.
+func testCodec() message.Codec {
+	var vocabulary []string
+	ids := map[string]int{}
+	return message.Codec{
+		Tokenizer: message.TokenizerFunc(func(s string) ([]int, error) {
+			var tokens []int
+			for _, word := range strings.Fields(s) {
+				id, ok := ids[word]
+				if !ok {
+					id = len(vocabulary)
+					ids[word] = id
+					vocabulary = append(vocabulary, word)
+				}
+				tokens = append(tokens, id)
+			}
+			return tokens, nil
+		}),
+		Detokenizer: message.DetokenizerFunc(func(tokens []int) (string, error) {
+			words := make([]string, len(tokens))
+			for i, id := range tokens {
+				words[i] = vocabulary[id]
+			}
+			return strings.Join(words, " "), nil
+		}),
+	}
+}
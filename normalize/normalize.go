@@ -0,0 +1,229 @@
+package normalize
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/fingerprint"
+	"github.com/bradfair/chat/message"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is the kind of change a normalizer made to a message.
+type Kind string
+
+const (
+	// KindCharacters marks a message whose line endings, spaces or invisible characters were normalized.
+	KindCharacters Kind = "characters"
+	// KindWhitespace marks a message whose whitespace was trimmed or collapsed.
+	KindWhitespace Kind = "whitespace"
+	// KindDuplicate marks a message that was removed because it repeated the message before it.
+	KindDuplicate Kind = "duplicate"
+	// KindMerge marks a message that was merged into the message before it because both were sent from the same role.
+	KindMerge Kind = "merge"
+)
+
+// Change describes one change a normalizer made.
+type Change struct {
+	Kind Kind
+	// Index is the index of the affected message in the original conversation.
+	Index int
+	// Result is the index of the message in the normalized conversation that the affected message became, was merged
+	// into, or duplicated.
+	Result int
+}
+
+// Report lists the changes a normalizer made, in the order they were made.
+type Report []Change
+
+// Changed returns true if the normalizer changed anything.
+func (r Report) Changed() bool {
+	return len(r) > 0
+}
+
+// Count returns the number of changes of the given kind.
+func (r Report) Count(kind Kind) int {
+	var n int
+	for _, c := range r {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Normalizer cleans up conversations by normalizing message content, collapsing repeated messages and merging adjacent
+// messages from the same role.
+type Normalizer struct {
+	characters bool
+	whitespace bool
+	duplicates bool
+	merge      bool
+	separator  string
+}
+
+// Normalize returns a normalized child of the given conversation along with a report of what was changed. The steps
+// run in this order: character cleanup, whitespace normalization, collapsing duplicates and merging. Messages whose
+// content changes lose their parts, if any.
+func (n Normalizer) Normalize(c *conversation.Conversation) (*conversation.Conversation, Report) {
+	var report Report
+	var result []message.Message
+	for i, m := range c.Messages() {
+		msg := message.From(m)
+		content := msg.Content()
+		var kinds []Kind
+		if n.characters {
+			if normalized := Characters(content); normalized != content {
+				content = normalized
+				kinds = append(kinds, KindCharacters)
+			}
+		}
+		if n.whitespace {
+			if normalized := Whitespace(content); normalized != content {
+				content = normalized
+				kinds = append(kinds, KindWhitespace)
+			}
+		}
+		if content != msg.Content() {
+			msg = msg.WithContent(content)
+		}
+
+		last := len(result) - 1
+		switch {
+		case last >= 0 && n.duplicates && fingerprint.Message(result[last]) == fingerprint.Message(msg):
+			kinds = append(kinds, KindDuplicate)
+		case last >= 0 && n.merge && result[last].Role() == msg.Role() && result[last].Name() == msg.Name():
+			result[last] = result[last].WithContent(result[last].Content() + n.separator + msg.Content())
+			kinds = append(kinds, KindMerge)
+		default:
+			result = append(result, msg)
+			last++
+		}
+		for _, kind := range kinds {
+			report = append(report, Change{Kind: kind, Index: i, Result: last})
+		}
+	}
+	messages := make([]conversation.Message, len(result))
+	for i, m := range result {
+		messages[i] = m
+	}
+	return c.NewChild().WithMessages(messages...), report
+}
+
+// WithCharacters configures whether a normalizer normalizes line endings, spaces and invisible characters with
+// Characters. The default is true.
+func (n Normalizer) WithCharacters(enabled bool) Normalizer {
+	n.characters = enabled
+	return n
+}
+
+// WithWhitespace configures whether a normalizer trims and collapses whitespace with Whitespace. The default is false,
+// since collapsing spaces can change the meaning of code and other preformatted text outside fenced code blocks.
+func (n Normalizer) WithWhitespace(enabled bool) Normalizer {
+	n.whitespace = enabled
+	return n
+}
+
+// WithCollapseDuplicates configures whether a normalizer removes messages that repeat the message before them, such as
+// retried user messages. Messages are compared by role, content, name and parts after their content is normalized.
+// The default is true.
+func (n Normalizer) WithCollapseDuplicates(enabled bool) Normalizer {
+	n.duplicates = enabled
+	return n
+}
+
+// WithMerge configures whether a normalizer merges adjacent messages sent from the same role and name into one
+// message, joining their content with the given separator. The merged message keeps the metadata of the first message.
+// Merging is enabled by default, with a blank line as the separator.
+func (n Normalizer) WithMerge(enabled bool, separator string) Normalizer {
+	n.merge = enabled
+	n.separator = separator
+	return n
+}
+
+// New creates a new normalizer with every step except whitespace normalization enabled.
+func New() Normalizer {
+	return Normalizer{characters: true, duplicates: true, merge: true, separator: "\n\n"}
+}
+
+// invisible lists characters that have no visible width and are removed by Characters.
+var invisible = map[rune]bool{
+	'\u00ad': true, // soft hyphen
+	'\u200b': true, // zero width space
+	'\u200c': true, // zero width non-joiner
+	'\u200d': true, // zero width joiner
+	'\u2060': true, // word joiner
+	'\ufeff': true, // byte order mark
+}
+
+// Characters returns s with CRLF and CR line endings converted to LF, non-breaking and other Unicode spaces converted
+// to ASCII spaces, invisible and control characters other than newlines and tabs removed, and the result in Unicode
+// normalization form NFC, so text written with composed and decomposed characters compares equal.
+func Characters(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case invisible[r] || unicode.IsControl(r):
+			return -1
+		case unicode.Is(unicode.Zs, r):
+			return ' '
+		}
+		return r
+	}, s))
+}
+
+var spaces = regexp.MustCompile(`(\S)[ \t]+`)
+
+// Whitespace returns s with runs of spaces and tabs between words collapsed to a single space, trailing whitespace
+// removed from each line, runs of more than one blank line collapsed to one, and leading and trailing whitespace
+// trimmed. Indentation at the start of each line is preserved, as are two trailing spaces marking a Markdown line
+// break. Fenced code blocks, delimited by lines starting with ``` or ~~~, are left untouched.
+func Whitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+	var fence string
+	var blank int
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t") == "" {
+				fence = ""
+			}
+			result = append(result, line)
+			continue
+		}
+		normalized := strings.TrimRight(spaces.ReplaceAllString(line, "$1 "), " \t")
+		switch {
+		case normalized == "":
+			if blank++; blank > 1 {
+				continue
+			}
+		case opening(trimmed) != "":
+			fence = opening(trimmed)
+			normalized = strings.TrimRight(line, " \t")
+			blank = 0
+		default:
+			if strings.HasSuffix(line, "  ") {
+				normalized += "  "
+			}
+			blank = 0
+		}
+		result = append(result, normalized)
+	}
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
+
+// opening returns the fence that opens a fenced code block on the given line, or an empty string if the line doesn't
+// open one.
+func opening(line string) string {
+	for _, c := range []string{"`", "~"} {
+		if n := len(line) - len(strings.TrimLeft(line, c)); n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
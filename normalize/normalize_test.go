@@ -0,0 +1,102 @@
+package normalize_test
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/normalize"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizer(t *testing.T) {
+	c := conversation.New().WithMessages(
+		message.New().WithRole("user").WithContent("Hello\u00a0there!"),
+		message.New().WithRole("user").WithContent("Hello there!"),
+		message.New().WithRole("assistant").WithContent("Hi!"),
+		message.New().WithRole("assistant").WithContent("How can I  help?"),
+		message.New().WithRole("user").WithContent("Thanks\u200b").WithName("alice"),
+		message.New().WithRole("user").WithContent("Bye").WithName("bob"),
+	)
+	t.Run("defaults", func(t *testing.T) {
+		normalized, report := normalize.New().Normalize(c)
+		want := "user: Hello there!\nassistant: Hi!\n\nHow can I  help?\nuser: Thanks\nuser: Bye"
+		if got := normalized.Messages().Transcript(); got != want {
+			t.Errorf("expected transcript to be %q, got %q", want, got)
+		}
+		if normalized.Parent() != c || c.Messages().Len() != 6 || len(c.Children()) != 0 {
+			t.Errorf("expected a child conversation, leaving the original unchanged")
+		}
+		wantReport := normalize.Report{
+			{Kind: normalize.KindCharacters, Index: 0, Result: 0},
+			{Kind: normalize.KindDuplicate, Index: 1, Result: 0},
+			{Kind: normalize.KindMerge, Index: 3, Result: 1},
+			{Kind: normalize.KindCharacters, Index: 4, Result: 2},
+		}
+		if !reflect.DeepEqual(report, wantReport) {
+			t.Errorf("expected report %+v, got %+v", wantReport, report)
+		}
+		if !report.Changed() || report.Count(normalize.KindCharacters) != 2 || report.Count(normalize.KindWhitespace) != 0 {
+			t.Errorf("unexpected counts in report %+v", report)
+		}
+	})
+	t.Run("configured", func(t *testing.T) {
+		normalized, report := normalize.New().
+			WithCharacters(false).
+			WithWhitespace(true).
+			WithCollapseDuplicates(false).
+			WithMerge(true, " / ").
+			Normalize(c)
+		want := "user: Hello\u00a0there! / Hello there!\nassistant: Hi! / How can I help?\nuser: Thanks\u200b\nuser: Bye"
+		if got := normalized.Messages().Transcript(); got != want {
+			t.Errorf("expected transcript to be %q, got %q", want, got)
+		}
+		if report.Count(normalize.KindCharacters) != 0 || report.Count(normalize.KindDuplicate) != 0 || report.Count(normalize.KindWhitespace) != 1 {
+			t.Errorf("expected only the enabled steps to run, got %+v", report)
+		}
+	})
+	t.Run("composed and decomposed duplicates", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("user").WithContent("Caf\u00e9?"),
+			message.New().WithRole("user").WithContent("Cafe\u0301?"),
+		)
+		normalized, report := normalize.New().Normalize(c)
+		if normalized.Messages().Len() != 1 || report.Count(normalize.KindDuplicate) != 1 {
+			t.Errorf("expected the messages to be collapsed, got %+v", report)
+		}
+	})
+	t.Run("nothing to change", func(t *testing.T) {
+		clean := conversation.New().WithMessages(message.New().WithRole("user").WithContent("Hello!"))
+		if _, report := normalize.New().WithWhitespace(true).Normalize(clean); report.Changed() {
+			t.Errorf("expected no changes, got %+v", report)
+		}
+	})
+}
+
+func TestWhitespace(t *testing.T) {
+	t.Run("prose", func(t *testing.T) {
+		got := normalize.Whitespace("  Some   text,\t\n\tindented   text  \nand a break.\n\n\n\nDone. ")
+		want := "Some text,\n\tindented text  \nand a break.\n\nDone."
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+	t.Run("fenced code", func(t *testing.T) {
+		code := "```go\nfunc main() {\n\tfmt.Println(\"a   b\")  \n\n\n\n}\n```"
+		got := normalize.Whitespace("Run  this:\n\n" + code + "\n~~~\nx  =  1\n~~~~\n\n\nDone.")
+		want := "Run this:\n\n" + code + "\n~~~\nx  =  1\n~~~~\n\nDone."
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+}
+
+func TestCharacters(t *testing.T) {
+	got := normalize.Characters("\ufeffa\u00a0b c\rd\r\ne\u0007\tf\u00adg")
+	want := "a b c\nd\ne\tfg"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := normalize.Characters("Cafe\u0301"); got != "Caf\u00e9" {
+		t.Errorf("expected decomposed characters to be composed, got %q", got)
+	}
+}
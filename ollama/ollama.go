@@ -0,0 +1,252 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/render"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of a local Ollama server.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Provider is the provider name used to look up wire roles in a message.Registry.
+const Provider = "ollama"
+
+// MetadataDoneReason is the metadata key holding the reason the model stopped generating a message.
+const MetadataDoneReason = "done_reason"
+
+// Endpoint is an Ollama API endpoint that generates completions.
+type Endpoint string
+
+const (
+	// Chat is the /api/chat endpoint, which accepts a list of messages and applies the model's own prompt template.
+	Chat Endpoint = "/api/chat"
+	// Generate is the /api/generate endpoint, which accepts a raw prompt rendered by the client's renderer.
+	Generate Endpoint = "/api/generate"
+)
+
+// Message is a message in the shape expected by the chat endpoint.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is the body of a chat or generate request. Messages are only sent to the chat endpoint, and Prompt and Raw
+// are only sent to the generate endpoint.
+type Request struct {
+	Model    string         `json:"model"`
+	Messages []Message      `json:"messages,omitempty"`
+	Prompt   string         `json:"prompt,omitempty"`
+	Raw      bool           `json:"raw,omitempty"`
+	Stream   bool           `json:"stream"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+// Response is the body of a chat or generate response, or a single chunk of a streamed response.
+type Response struct {
+	Model           string   `json:"model"`
+	Message         *Message `json:"message,omitempty"`
+	Response        string   `json:"response"`
+	Done            bool     `json:"done"`
+	DoneReason      string   `json:"done_reason"`
+	PromptEvalCount int      `json:"prompt_eval_count"`
+	EvalCount       int      `json:"eval_count"`
+	Error           string   `json:"error"`
+}
+
+// content returns the generated content of a response from either endpoint.
+func (r Response) content() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
+
+// Client is a completer that sends conversations to an Ollama server.
+type Client struct {
+	baseURL    string
+	model      string
+	endpoint   Endpoint
+	renderer   render.Renderer
+	options    map[string]any
+	httpClient *http.Client
+	tokenizer  message.Tokenizer
+	registry   *message.Registry
+}
+
+// NewRequest returns the request the client sends for a conversation. If the client has a registry, each message's
+// role is mapped to the role it is sent as with the registry's Resolve method, before the prompt is rendered for the
+// generate endpoint.
+func (c Client) NewRequest(convo *conversation.Conversation, stream bool) (Request, error) {
+	request := Request{Model: c.model, Stream: stream, Options: c.options}
+	messages := convo.Messages()
+	mapped := make([]conversation.Message, len(messages))
+	for i, m := range messages {
+		wire, err := c.registry.Resolve(message.Role(m.Role()), Provider)
+		if err != nil {
+			return Request{}, err
+		}
+		mapped[i] = m
+		if wire != m.Role() {
+			mapped[i] = message.From(m).WithRole(message.Role(wire))
+		}
+	}
+	if c.endpoint == Generate {
+		request.Prompt = c.renderer.Render(convo.NewChild().WithMessages(mapped...))
+		request.Raw = true
+		return request, nil
+	}
+	for _, m := range mapped {
+		request.Messages = append(request.Messages, Message{Role: m.Role(), Content: m.Content()})
+	}
+	return request, nil
+}
+
+// Complete implements the completion.Completer interface.
+func (c Client) Complete(ctx context.Context, convo *conversation.Conversation) (message.Message, error) {
+	return c.send(ctx, convo, false, nil)
+}
+
+// Stream implements the completion.Streamer interface.
+func (c Client) Stream(ctx context.Context, convo *conversation.Conversation, fn func(chunk string) error) (message.Message, error) {
+	return c.send(ctx, convo, true, fn)
+}
+
+// send sends a request and reads the response, which is a single JSON object, or one JSON object per line when
+// streaming.
+func (c Client) send(ctx context.Context, convo *conversation.Conversation, stream bool, fn func(chunk string) error) (message.Message, error) {
+	request, err := c.NewRequest(convo, stream)
+	if err != nil {
+		return message.Message{}, err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.baseURL, "/")+string(c.endpoint), bytes.NewReader(body))
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(resp.Body)
+		return message.Message{}, completion.NewStatusError(resp, b)
+	}
+
+	var content strings.Builder
+	var last Response
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk Response
+		if err := decoder.Decode(&chunk); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return message.Message{}, fmt.Errorf("could not decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return message.Message{}, fmt.Errorf("server error: %s", chunk.Error)
+		}
+		content.WriteString(chunk.content())
+		if fn != nil && chunk.content() != "" {
+			if err := fn(chunk.content()); err != nil {
+				return message.Message{}, err
+			}
+		}
+		last = chunk
+		if chunk.Done {
+			break
+		}
+	}
+	if !last.Done {
+		return message.Message{}, fmt.Errorf("response ended before it was done: %w", io.ErrUnexpectedEOF)
+	}
+
+	m := message.New().
+		WithRole(message.RoleAssistant).
+		WithContent(content.String()).
+		WithTokenizer(c.tokenizer).
+		WithMetadata(completion.MetadataModel, last.Model).
+		WithMetadata(MetadataDoneReason, last.DoneReason)
+	return completion.WithUsage(m, completion.Usage{PromptTokens: last.PromptEvalCount, CompletionTokens: last.EvalCount}), nil
+}
+
+// WithBaseURL configures a client with the base URL of the server.
+func (c Client) WithBaseURL(url string) Client {
+	c.baseURL = url
+	return c
+}
+
+// WithModel configures a client with the model to use.
+func (c Client) WithModel(model string) Client {
+	c.model = model
+	return c
+}
+
+// WithEndpoint configures a client with the endpoint used to generate completions. The default is Chat.
+func (c Client) WithEndpoint(endpoint Endpoint) Client {
+	c.endpoint = endpoint
+	return c
+}
+
+// WithRenderer configures a client with the renderer used to build prompts for the Generate endpoint.
+// The default is render.ChatML.
+func (c Client) WithRenderer(r render.Renderer) Client {
+	c.renderer = r
+	return c
+}
+
+// WithOption configures a client with a model option, such as "temperature" or "num_ctx".
+func (c Client) WithOption(key string, value any) Client {
+	options := make(map[string]any, len(c.options)+1)
+	for k, v := range c.options {
+		options[k] = v
+	}
+	options[key] = value
+	c.options = options
+	return c
+}
+
+// WithHTTPClient configures a client with the HTTP client used to send requests.
+func (c Client) WithHTTPClient(client *http.Client) Client {
+	c.httpClient = client
+	return c
+}
+
+// WithRegistry configures a client with the registry used to map roles to the roles sent to the server. If the
+// registry is strict, conversations with unregistered roles are rejected with an error wrapping
+// message.ErrUnregisteredRole.
+func (c Client) WithRegistry(r *message.Registry) Client {
+	c.registry = r
+	return c
+}
+
+// WithTokenizer configures a client with the tokenizer given to response messages.
+func (c Client) WithTokenizer(t message.Tokenizer) Client {
+	c.tokenizer = t
+	return c
+}
+
+// New creates a new client using the default base URL, the chat endpoint, and the default HTTP client.
+func New() Client {
+	c := Client{
+		baseURL:    DefaultBaseURL,
+		endpoint:   Chat,
+		renderer:   render.ChatML{},
+		httpClient: http.DefaultClient,
+	}
+	return c
+}
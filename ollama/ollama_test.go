@@ -0,0 +1,156 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/ollama"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	c := conversation.New().WithMessages(
+		message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+		message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+	)
+	t.Run("chat", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/chat" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			var request ollama.Request
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("could not decode request: %v", err)
+			}
+			want := []ollama.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hello!"}}
+			if request.Model != "llama2" || request.Stream || !reflect.DeepEqual(request.Messages, want) || request.Options["temperature"] != 0.5 {
+				t.Errorf("unexpected request %+v", request)
+			}
+			_, _ = w.Write([]byte(`{"model":"llama2","message":{"role":"assistant","content":"Hi there!"},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":4}`))
+		}))
+		defer server.Close()
+		m, err := ollama.New().WithBaseURL(server.URL).WithModel("llama2").WithOption("temperature", 0.5).Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Role() != "assistant" || m.Content() != "Hi there!" {
+			t.Errorf("unexpected message %s: %s", m.Role(), m.Content())
+		}
+		if usage := completion.UsageOf(m); usage != (completion.Usage{PromptTokens: 10, CompletionTokens: 4}) {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+		if m.Metadata()[ollama.MetadataDoneReason] != "stop" {
+			t.Errorf("unexpected metadata %v", m.Metadata())
+		}
+	})
+	t.Run("stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"model":"llama2","message":{"role":"assistant","content":"Hi"},"done":false}
+{"model":"llama2","message":{"role":"assistant","content":" there!"},"done":false}
+{"model":"llama2","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":10,"eval_count":2}
+`))
+		}))
+		defer server.Close()
+		var chunks []string
+		m, err := ollama.New().WithBaseURL(server.URL).Stream(context.Background(), c, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(chunks, []string{"Hi", " there!"}) {
+			t.Errorf("unexpected chunks %q", chunks)
+		}
+		if m.Content() != "Hi there!" {
+			t.Errorf("unexpected content %q", m.Content())
+		}
+		if usage := completion.UsageOf(m); usage.TotalTokens() != 12 {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+	})
+	t.Run("stream callback error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"response":"Hi","done":false}` + "\n"))
+		}))
+		defer server.Close()
+		errStop := errors.New("stop")
+		_, err := ollama.New().WithBaseURL(server.URL).WithEndpoint(ollama.Generate).Stream(context.Background(), c, func(string) error {
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("expected %v, got %v", errStop, err)
+		}
+	})
+	t.Run("stream ends early", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"model":"llama2","message":{"role":"assistant","content":"Hi"},"done":false}` + "\n"))
+		}))
+		defer server.Close()
+		_, err := ollama.New().WithBaseURL(server.URL).Stream(context.Background(), c, func(string) error { return nil })
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+		}
+	})
+	t.Run("generate", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/generate" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			var request ollama.Request
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("could not decode request: %v", err)
+			}
+			want := "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHello!<|im_end|>\n<|im_start|>assistant\n"
+			if !request.Raw || request.Prompt != want || request.Messages != nil {
+				t.Errorf("unexpected request %+v", request)
+			}
+			_, _ = w.Write([]byte(`{"response":"Hi!","done":true,"prompt_eval_count":10,"eval_count":1}`))
+		}))
+		defer server.Close()
+		m, err := ollama.New().WithBaseURL(server.URL).WithEndpoint(ollama.Generate).Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Content() != "Hi!" {
+			t.Errorf("unexpected content %q", m.Content())
+		}
+	})
+	t.Run("registry", func(t *testing.T) {
+		registry := message.NewRegistry()
+		registry.Register("narrator", map[string]string{ollama.Provider: "assistant"})
+		registry.SetStrict(true)
+		client := ollama.New().WithRegistry(registry)
+		narrated := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+			message.New().WithRole("narrator").WithContent("Meanwhile..."),
+		)
+		request, err := client.NewRequest(narrated, false)
+		if err != nil || request.Messages[1].Role != "assistant" {
+			t.Errorf("expected the narrator to be sent as the assistant, got %+v and %v", request.Messages, err)
+		}
+		narrated.Append(message.New().WithRole("critic").WithContent("Too long."))
+		if _, err := client.Complete(context.Background(), narrated); !errors.Is(err, message.ErrUnregisteredRole) {
+			t.Errorf("expected ErrUnregisteredRole, got %v", err)
+		}
+	})
+	t.Run("server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model not found"}`))
+		}))
+		defer server.Close()
+		_, err := ollama.New().WithBaseURL(server.URL).Complete(context.Background(), c)
+		var statusErr *completion.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Errorf("expected a 404 StatusError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/internal/lcs"
+	"github.com/bradfair/chat/message"
+	"reflect"
+)
+
+// Transform produces a new conversation from an existing one. Transforms should leave the given conversation
+// unchanged, typically by returning a child created with NewChild and WithMessages.
+type Transform func(c *conversation.Conversation) (*conversation.Conversation, error)
+
+// Condition decides whether a step applies to a conversation.
+type Condition func(c *conversation.Conversation) bool
+
+// step is a named transform, applied only when its condition holds.
+type step struct {
+	name      string
+	transform Transform
+	when      Condition
+}
+
+// Pipeline applies a sequence of transforms to a conversation, in the order they were added.
+type Pipeline struct {
+	steps []step
+}
+
+// Apply runs every step of the pipeline and returns the resulting conversation. Apply has the same signature as a
+// Transform, so pipelines can be nested within one another.
+func (p Pipeline) Apply(c *conversation.Conversation) (*conversation.Conversation, error) {
+	result, _, err := p.Trace(c)
+	return result, err
+}
+
+// Trace runs every step of the pipeline like Apply, and also returns a record of what each step changed.
+// If a step fails, the trace includes the steps that ran before it.
+func (p Pipeline) Trace(c *conversation.Conversation) (*conversation.Conversation, Trace, error) {
+	trace := make(Trace, 0, len(p.steps))
+	for _, s := range p.steps {
+		if s.when != nil && !s.when(c) {
+			trace = append(trace, StepTrace{Name: s.name, Skipped: true})
+			continue
+		}
+		before := c.Messages()
+		next, err := s.transform(c)
+		if err != nil {
+			return nil, trace, fmt.Errorf("step %q failed: %w", s.name, err)
+		}
+		trace = append(trace, StepTrace{Name: s.name, Changes: changes(before, next.Messages())})
+		c = next
+	}
+	return c, trace, nil
+}
+
+// Wrap returns a completer that applies the pipeline to each conversation before passing it to the next completer.
+func (p Pipeline) Wrap(next completion.Completer) completion.Completer {
+	return completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		transformed, err := p.Apply(c)
+		if err != nil {
+			return message.Message{}, err
+		}
+		return next.Complete(ctx, transformed)
+	})
+}
+
+// With configures a pipeline with an additional step, run after the steps already added.
+func (p Pipeline) With(name string, t Transform) Pipeline {
+	return p.WithIf(name, nil, t)
+}
+
+// WithIf configures a pipeline with an additional step that only runs when the condition holds for the conversation
+// produced by the steps before it. A nil condition always holds.
+func (p Pipeline) WithIf(name string, when Condition, t Transform) Pipeline {
+	steps := make([]step, len(p.steps), len(p.steps)+1)
+	copy(steps, p.steps)
+	p.steps = append(steps, step{name: name, transform: t, when: when})
+	return p
+}
+
+// New creates a new, empty pipeline.
+func New() Pipeline {
+	return Pipeline{}
+}
+
+// Trace records what each step of a pipeline did, in order.
+type Trace []StepTrace
+
+// Changed returns true if any step changed the conversation.
+func (t Trace) Changed() bool {
+	for _, s := range t {
+		if len(s.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StepTrace records what a single step of a pipeline did.
+type StepTrace struct {
+	// Name is the name the step was added with.
+	Name string
+	// Skipped is true if the step's condition did not hold.
+	Skipped bool
+	// Changes lists the messages the step added, removed or modified.
+	Changes []Change
+}
+
+// Change describes a message a step added, removed or modified. Before is nil for messages the step added, and After
+// is nil for messages it removed. Index is the index of the message after the step, or before it for removed messages.
+type Change struct {
+	Index  int
+	Before conversation.Message
+	After  conversation.Message
+}
+
+// changes aligns two lists of messages by their longest common subsequence. Within each run of differing messages,
+// the n-th removed message and the n-th added message are reported together as a modification.
+func changes(before, after conversation.Messages) []Change {
+	edits := lcs.Align(len(before), len(after), func(i, j int) bool { return equal(before[i], after[j]) })
+	var changes []Change
+	for k := 0; k < len(edits); {
+		if edits[k].Op == lcs.Equal {
+			k++
+			continue
+		}
+		var removed, added []int
+		for ; k < len(edits) && edits[k].Op != lcs.Equal; k++ {
+			if edits[k].Op == lcs.Delete {
+				removed = append(removed, edits[k].I)
+			} else {
+				added = append(added, edits[k].J)
+			}
+		}
+		n := 0
+		for ; n < len(removed) && n < len(added); n++ {
+			changes = append(changes, Change{Index: added[n], Before: before[removed[n]], After: after[added[n]]})
+		}
+		for _, i := range removed[n:] {
+			changes = append(changes, Change{Index: i, Before: before[i]})
+		}
+		for _, j := range added[n:] {
+			changes = append(changes, Change{Index: j, After: after[j]})
+		}
+	}
+	return changes
+}
+
+// equal returns true if two messages have the same role, content and metadata.
+func equal(a, b conversation.Message) bool {
+	if a.Role() != b.Role() || a.Content() != b.Content() {
+		return false
+	}
+	return reflect.DeepEqual(metadata(a), metadata(b))
+}
+
+// metadata returns the message's metadata, or an empty map if it has none.
+func metadata(m conversation.Message) map[string]string {
+	if md, ok := m.(interface{ Metadata() map[string]string }); ok && len(md.Metadata()) > 0 {
+		return md.Metadata()
+	}
+	return map[string]string{}
+}
@@ -0,0 +1,150 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/pipeline"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Run("applies steps in order", func(t *testing.T) {
+		c := testConversation()
+		p := pipeline.New().
+			With("trim", pipeline.TrimSpace()).
+			With("system", pipeline.InjectSystemPrompt("Be brief.")).
+			With("roles", pipeline.TranslateRoles(map[string]string{"assistant": "model"}))
+		result, err := p.Apply(c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := "system: Be brief.\nuser: Hello!\nmodel: Hi there!"
+		if got := result.Messages().Transcript(); got != want {
+			t.Errorf("expected transcript to be %q, got %q", want, got)
+		}
+		if c.Messages().Len() != 2 || c.Message(0).Content() != "  Hello!  " {
+			t.Errorf("expected original conversation to be unchanged")
+		}
+		for parent := result.Parent(); parent != c; parent = parent.Parent() {
+			if parent == nil {
+				t.Fatalf("expected result to descend from the original conversation")
+			}
+		}
+		if len(c.Children()) != 0 {
+			t.Errorf("expected transforms not to record children, got %d", len(c.Children()))
+		}
+	})
+	t.Run("trace", func(t *testing.T) {
+		p := pipeline.New().
+			With("trim", pipeline.TrimSpace()).
+			WithIf("system", pipeline.Not(pipeline.HasRole("system")), pipeline.InjectSystemPrompt("Be brief.")).
+			With("strip", pipeline.StripMetadata("internal")).
+			WithIf("again", pipeline.Not(pipeline.HasRole("system")), pipeline.InjectSystemPrompt("Be kind."))
+		_, trace, err := p.Trace(testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(trace) != 4 || !trace.Changed() {
+			t.Fatalf("unexpected trace %+v", trace)
+		}
+		if trim := trace[0].Changes; len(trim) != 1 || trim[0].Index != 0 || trim[0].After.Content() != "Hello!" {
+			t.Errorf("unexpected trim changes %+v", trim)
+		}
+		if system := trace[1].Changes; len(system) != 1 || system[0].Index != 0 || system[0].Before != nil || system[0].After.Content() != "Be brief." {
+			t.Errorf("unexpected system changes %+v", system)
+		}
+		if strip := trace[2].Changes; len(strip) != 1 || strip[0].Index != 2 {
+			t.Errorf("unexpected strip changes %+v", strip)
+		}
+		if !trace[3].Skipped || trace[3].Changes != nil {
+			t.Errorf("expected last step to be skipped, got %+v", trace[3])
+		}
+	})
+	t.Run("inject system prompt is idempotent", func(t *testing.T) {
+		p := pipeline.New().With("first", pipeline.InjectSystemPrompt("Be brief.")).With("second", pipeline.InjectSystemPrompt("Be brief."))
+		_, trace, err := p.Trace(testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(trace[1].Changes) != 0 {
+			t.Errorf("expected second step to change nothing, got %+v", trace[1].Changes)
+		}
+	})
+	t.Run("trace aligns messages", func(t *testing.T) {
+		dropFirst := func(c *conversation.Conversation) (*conversation.Conversation, error) {
+			return conversation.New().WithParent(c).WithMessages(c.Messages()[1:]...), nil
+		}
+		_, trace, err := pipeline.New().With("drop", dropFirst).Trace(testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if drop := trace[0].Changes; len(drop) != 1 || drop[0].Index != 0 || drop[0].After != nil || drop[0].Before.Content() != "  Hello!  " {
+			t.Errorf("expected a single removal, got %+v", drop)
+		}
+	})
+	t.Run("results can be edited", func(t *testing.T) {
+		c := testConversation()
+		c.WithMessages(append([]conversation.Message{message.New().WithRole(message.RoleSystem).WithContent("Be brief.")}, c.Messages()...)...)
+		for name, transform := range map[string]pipeline.Transform{
+			"inject":   pipeline.InjectSystemPrompt("Be brief."),
+			"trim":     pipeline.TrimSpace(),
+			"pipeline": pipeline.New().With("inject", pipeline.InjectSystemPrompt("Be brief.")).Apply,
+		} {
+			result, err := transform(c)
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", name, err)
+			}
+			result.Remove(0)
+			result.Append(message.New().WithRole(message.RoleUser).WithContent("Bye!"))
+			if c.Messages().Len() != 3 || c.Message(0).Content() != "Be brief." || c.Message(2).Content() != "Hi there!" {
+				t.Errorf("%s: expected the original conversation to be unchanged, got %q", name, c.Messages().Transcript())
+			}
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		failure := errors.New("failure")
+		p := pipeline.New().
+			With("trim", pipeline.TrimSpace()).
+			With("fail", func(c *conversation.Conversation) (*conversation.Conversation, error) { return nil, failure })
+		_, trace, err := p.Trace(testConversation())
+		if !errors.Is(err, failure) {
+			t.Errorf("expected failure, got %v", err)
+		}
+		if len(trace) != 1 {
+			t.Errorf("expected trace to include the steps before the failure, got %+v", trace)
+		}
+	})
+	t.Run("nested", func(t *testing.T) {
+		inner := pipeline.New().With("trim", pipeline.TrimSpace())
+		result, err := pipeline.New().With("inner", inner.Apply).Apply(testConversation())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Message(0).Content() != "Hello!" {
+			t.Errorf("expected nested pipeline to run, got %q", result.Message(0).Content())
+		}
+	})
+	t.Run("wrap", func(t *testing.T) {
+		var sent string
+		completer := pipeline.New().With("trim", pipeline.TrimSpace()).Wrap(completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+			sent = c.Message(0).Content()
+			return message.New().WithRole(message.RoleAssistant).WithContent("Hi!"), nil
+		}))
+		if _, err := completer.Complete(context.Background(), testConversation()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sent != "Hello!" {
+			t.Errorf("expected transformed conversation to be sent, got %q", sent)
+		}
+	})
+}
+
+func testConversation() *conversation.Conversation {
+	return conversation.New().WithMessages(
+		message.New().WithRole(message.RoleUser).WithContent("  Hello!  "),
+		message.New().WithRole(message.RoleAssistant).WithContent("Hi there!").WithMetadata("internal", "true"),
+	)
+}
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+)
+
+// Map returns a transform that replaces each message with the result of fn. Messages that are not message.Message
+// values are converted with message.From before fn is called.
+func Map(fn func(m message.Message) message.Message) Transform {
+	return func(c *conversation.Conversation) (*conversation.Conversation, error) {
+		messages := c.Messages()
+		mapped := make([]conversation.Message, len(messages))
+		for i, m := range messages {
+			mapped[i] = fn(message.From(m))
+		}
+		return c.NewChild().WithMessages(mapped...), nil
+	}
+}
+
+// TrimSpace returns a transform that removes leading and trailing whitespace from the content of every message.
+func TrimSpace() Transform {
+	return Map(func(m message.Message) message.Message {
+		if trimmed := strings.TrimSpace(m.Content()); trimmed != m.Content() {
+			return m.WithContent(trimmed)
+		}
+		return m
+	})
+}
+
+// InjectSystemPrompt returns a transform that prepends a system message with the given prompt, unless the conversation
+// already starts with that exact system message.
+func InjectSystemPrompt(prompt string) Transform {
+	return func(c *conversation.Conversation) (*conversation.Conversation, error) {
+		messages := c.Messages()
+		if len(messages) > 0 && messages[0].Role() == string(message.RoleSystem) && messages[0].Content() == prompt {
+			return c.NewChild().WithMessages(append([]conversation.Message(nil), messages...)...), nil
+		}
+		injected := make([]conversation.Message, 0, len(messages)+1)
+		injected = append(injected, message.New().WithRole(message.RoleSystem).WithContent(prompt))
+		injected = append(injected, messages...)
+		return c.NewChild().WithMessages(injected...), nil
+	}
+}
+
+// StripMetadata returns a transform that removes the given metadata keys from every message, or all metadata if no
+// keys are given.
+func StripMetadata(keys ...string) Transform {
+	return Map(func(m message.Message) message.Message {
+		return m.WithoutMetadata(keys...)
+	})
+}
+
+// TranslateRoles returns a transform that renames roles according to the given map, such as "assistant" to "model".
+// Roles that are not in the map are left unchanged.
+func TranslateRoles(roles map[string]string) Transform {
+	return Map(func(m message.Message) message.Message {
+		if role, ok := roles[m.Role()]; ok {
+			return m.WithRole(message.Role(role))
+		}
+		return m
+	})
+}
+
+// HasRole returns a condition that holds when the conversation contains at least one message with the given role.
+func HasRole(role string) Condition {
+	return func(c *conversation.Conversation) bool {
+		for _, m := range c.Messages() {
+			if m.Role() == role {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a condition that holds when the given condition does not.
+func Not(cond Condition) Condition {
+	return func(c *conversation.Conversation) bool {
+		return !cond(c)
+	}
+}
@@ -0,0 +1,238 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ErrMissingVariable is returned when a template is rendered without a required variable.
+var ErrMissingVariable = errors.New("missing variable")
+
+// ErrVariableType is returned when a template variable is supplied with a value of the wrong type.
+var ErrVariableType = errors.New("wrong variable type")
+
+// Vars holds the values of a template's variables, keyed by name.
+type Vars map[string]any
+
+// Template produces messages from a text/template source.
+type Template struct {
+	name      string
+	role      message.Role
+	tokenizer message.Tokenizer
+	tmpl      *template.Template
+	variables map[string]Type
+}
+
+// Name returns the name of the template.
+func (t Template) Name() string {
+	return t.name
+}
+
+// Variables returns the names of the variables required by the template, in sorted order.
+func (t Template) Variables() []string {
+	names := make([]string, 0, len(t.variables))
+	for name := range t.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate returns an error if any required variable is missing from vars or has the wrong type.
+func (t Template) Validate(vars Vars) error {
+	var errs []error
+	for _, name := range t.Variables() {
+		value, ok := vars[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w %q", ErrMissingVariable, name))
+			continue
+		}
+		if typ := t.variables[name]; !typ.matches(value) {
+			errs = append(errs, fmt.Errorf("%w: %q must be %s, got %T", ErrVariableType, name, typ, value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Render validates vars and executes the template, returning the result as a message.
+func (t Template) Render(vars Vars) (message.Message, error) {
+	if err := t.Validate(vars); err != nil {
+		return message.Message{}, fmt.Errorf("could not render template %q: %w", t.name, err)
+	}
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, map[string]any(vars)); err != nil {
+		return message.Message{}, fmt.Errorf("could not render template %q: %w", t.name, err)
+	}
+	return message.New().WithRole(t.role).WithContent(b.String()).WithTokenizer(t.tokenizer), nil
+}
+
+// WithRole configures a template with the role of the messages it produces.
+func (t Template) WithRole(role message.Role) Template {
+	t.role = role
+	return t
+}
+
+// WithTokenizer configures a template with the tokenizer of the messages it produces.
+func (t Template) WithTokenizer(tokenizer message.Tokenizer) Template {
+	t.tokenizer = tokenizer
+	return t
+}
+
+// WithVariable declares a required variable and its type. Variables referenced by the template source are declared
+// automatically with type Any.
+func (t Template) WithVariable(name string, typ Type) Template {
+	variables := make(map[string]Type, len(t.variables)+1)
+	for k, v := range t.variables {
+		variables[k] = v
+	}
+	variables[name] = typ
+	t.variables = variables
+	return t
+}
+
+// Parse creates a template from source. Templates produce user messages unless configured otherwise.
+func Parse(name, source string) (Template, error) {
+	tmpl, err := template.New(name).Funcs(Funcs()).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return Template{}, fmt.Errorf("could not parse template %q: %w", name, err)
+	}
+	t := Template{
+		name:      name,
+		role:      message.RoleUser,
+		tmpl:      tmpl,
+		variables: map[string]Type{},
+	}
+	for _, tree := range tmpl.Templates() {
+		collectVariables(tree.Tree.Root, t.variables)
+	}
+	return t, nil
+}
+
+// Must panics if err is non-nil and otherwise returns t. It is intended for templates parsed during initialization.
+func Must(t Template, err error) Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseFile creates a template from the file at the given path. The template is named after the file.
+func ParseFile(filename string) (Template, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return Template{}, fmt.Errorf("could not read template: %w", err)
+	}
+	return Parse(path.Base(filename), string(b))
+}
+
+// ParseFS creates a template from the file at the given path in fsys, such as an embed.FS. The template is named after
+// the file.
+func ParseFS(fsys fs.FS, filename string) (Template, error) {
+	b, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return Template{}, fmt.Errorf("could not read template: %w", err)
+	}
+	return Parse(path.Base(filename), string(b))
+}
+
+// Funcs returns the helper functions available to templates:
+//   - transcript returns the transcript of a *conversation.Conversation or conversation.Messages.
+//   - last returns the last message of a *conversation.Conversation or conversation.Messages.
+//   - join joins a slice of strings with a separator, e.g. {{ join ", " .Items }}.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"transcript": func(v any) (string, error) {
+			messages, err := toMessages(v)
+			if err != nil {
+				return "", err
+			}
+			return messages.Transcript(), nil
+		},
+		"last": func(v any) (conversation.Message, error) {
+			messages, err := toMessages(v)
+			if err != nil {
+				return nil, err
+			}
+			last := messages.Last()
+			if last == nil {
+				return nil, errors.New("no messages")
+			}
+			return last, nil
+		},
+		"join": func(sep string, elems []string) string {
+			return strings.Join(elems, sep)
+		},
+	}
+}
+
+func toMessages(v any) (conversation.Messages, error) {
+	switch v := v.(type) {
+	case *conversation.Conversation:
+		return v.Messages(), nil
+	case conversation.Messages:
+		return v, nil
+	case []conversation.Message:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("expected a conversation or messages, got %T", v)
+	}
+}
+
+// collectVariables records the top-level fields referenced by the template tree, such as Name in {{ .Name }}.
+func collectVariables(node parse.Node, variables map[string]Type) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, variables)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, variables)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectVariables(cmd, variables)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectVariables(arg, variables)
+		}
+	case *parse.ChainNode:
+		collectVariables(n.Node, variables)
+	case *parse.FieldNode:
+		if _, ok := variables[n.Ident[0]]; !ok {
+			variables[n.Ident[0]] = Any
+		}
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, variables)
+	case *parse.RangeNode:
+		// Fields inside a range refer to the elements being ranged over, not to top-level variables.
+		collectVariables(n.Pipe, variables)
+		collectVariables(n.ElseList, variables)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, variables)
+		collectVariables(n.ElseList, variables)
+	case *parse.TemplateNode:
+		collectVariables(n.Pipe, variables)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, variables map[string]Type) {
+	collectVariables(n.Pipe, variables)
+	collectVariables(n.List, variables)
+	collectVariables(n.ElseList, variables)
+}
@@ -0,0 +1,109 @@
+package prompt_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/prompt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Run("render", func(t *testing.T) {
+		tmpl, err := prompt.Parse("greeting", "Hello, {{ .Name }}!")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		m, err := tmpl.WithRole(message.RoleSystem).Render(prompt.Vars{"Name": "world"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Role() != "system" {
+			t.Errorf("expected role to be system, got %s", m.Role())
+		}
+		if m.Content() != "Hello, world!" {
+			t.Errorf("expected content to be Hello, world!, got %s", m.Content())
+		}
+	})
+	t.Run("variables", func(t *testing.T) {
+		tmpl, err := prompt.Parse("vars", `{{ .A }}{{ if .B }}{{ .C.D }}{{ end }}{{ range .E }}{{ .F }}{{ end }}{{ (last .G).Content }}`)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := []string{"A", "B", "C", "E", "G"}
+		if !reflect.DeepEqual(tmpl.Variables(), want) {
+			t.Errorf("expected variables %v, got %v", want, tmpl.Variables())
+		}
+	})
+	t.Run("missing variable", func(t *testing.T) {
+		tmpl, _ := prompt.Parse("greeting", "Hello, {{ .Name }}!")
+		_, err := tmpl.Render(prompt.Vars{})
+		if !errors.Is(err, prompt.ErrMissingVariable) {
+			t.Errorf("expected ErrMissingVariable, got %v", err)
+		}
+	})
+	t.Run("wrong variable type", func(t *testing.T) {
+		tmpl, _ := prompt.Parse("greeting", "Hello, {{ .Name }}!")
+		_, err := tmpl.WithVariable("Name", prompt.String).Render(prompt.Vars{"Name": 42})
+		if !errors.Is(err, prompt.ErrVariableType) {
+			t.Errorf("expected ErrVariableType, got %v", err)
+		}
+	})
+	t.Run("declared variable", func(t *testing.T) {
+		tmpl, _ := prompt.Parse("static", "Hello!")
+		_, err := tmpl.WithVariable("Conversation", prompt.Conversation).Render(prompt.Vars{})
+		if !errors.Is(err, prompt.ErrMissingVariable) {
+			t.Errorf("expected ErrMissingVariable, got %v", err)
+		}
+	})
+	t.Run("funcs", func(t *testing.T) {
+		c := conversation.New()
+		c.Append(message.New().WithRole("user").WithContent("hi"))
+		c.Append(message.New().WithRole("assistant").WithContent("hello"))
+		tmpl, err := prompt.Parse("funcs", `{{ transcript .Conversation }}|{{ (last .Conversation).Content }}|{{ join ", " .Items }}`)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		m, err := tmpl.
+			WithVariable("Conversation", prompt.Conversation).
+			WithVariable("Items", prompt.Strings).
+			Render(prompt.Vars{"Conversation": c, "Items": []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Content() != "user: hi\nassistant: hello|hello|a, b" {
+			t.Errorf("unexpected content %q", m.Content())
+		}
+	})
+	t.Run("parse file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "greeting.tmpl")
+		if err := os.WriteFile(filename, []byte("Hello, {{ .Name }}!"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		tmpl, err := prompt.ParseFile(filename)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if tmpl.Name() != "greeting.tmpl" {
+			t.Errorf("expected name to be greeting.tmpl, got %s", tmpl.Name())
+		}
+	})
+	t.Run("parse fs", func(t *testing.T) {
+		fsys := fstest.MapFS{"prompts/greeting.tmpl": {Data: []byte("Hello, {{ .Name }}!")}}
+		tmpl, err := prompt.ParseFS(fsys, "prompts/greeting.tmpl")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		m, err := tmpl.Render(prompt.Vars{"Name": "fs"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m.Content() != "Hello, fs!" {
+			t.Errorf("expected content to be Hello, fs!, got %s", m.Content())
+		}
+	})
+}
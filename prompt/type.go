@@ -0,0 +1,52 @@
+package prompt
+
+import "github.com/bradfair/chat/conversation"
+
+// Type is the type of a template variable.
+type Type string
+
+const (
+	// Any accepts a value of any type.
+	Any Type = "any"
+	// String accepts a string.
+	String Type = "string"
+	// Strings accepts a slice of strings, such as those passed to join.
+	Strings Type = "[]string"
+	// Int accepts an int.
+	Int Type = "int"
+	// Bool accepts a bool.
+	Bool Type = "bool"
+	// Conversation accepts a *conversation.Conversation.
+	Conversation Type = "conversation"
+	// Messages accepts conversation.Messages or a slice of conversation.Message.
+	Messages Type = "messages"
+)
+
+// matches returns true if the value is of the type.
+func (t Type) matches(v any) bool {
+	switch t {
+	case String:
+		_, ok := v.(string)
+		return ok
+	case Strings:
+		_, ok := v.([]string)
+		return ok
+	case Int:
+		_, ok := v.(int)
+		return ok
+	case Bool:
+		_, ok := v.(bool)
+		return ok
+	case Conversation:
+		c, ok := v.(*conversation.Conversation)
+		return ok && c != nil
+	case Messages:
+		switch v.(type) {
+		case conversation.Messages, []conversation.Message:
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
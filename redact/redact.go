@@ -0,0 +1,206 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pattern describes one kind of sensitive value, such as an email address.
+type Pattern struct {
+	// Name identifies the kind of value in placeholders, such as "EMAIL" in "[EMAIL_1]".
+	Name string
+	// Regexp matches candidate values.
+	Regexp *regexp.Regexp
+	// Valid, if set, is called with each match and must return true for the match to be redacted.
+	Valid func(match string) bool
+}
+
+// Built-in patterns, used by New in this order.
+var (
+	// CreditCard matches payment card numbers of 13 to 19 digits, optionally grouped with spaces or dashes, that pass
+	// the Luhn check.
+	CreditCard = Pattern{Name: "CREDIT_CARD", Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Valid: luhn}
+	// Email matches email addresses.
+	Email = Pattern{Name: "EMAIL", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)}
+	// Phone matches ten-digit phone numbers with an optional country code, such as "(555) 123-4567" or "+1 555.123.4567".
+	Phone = Pattern{Name: "PHONE", Regexp: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.-]?)\d{3}[\s.-]?\d{4}\b`)}
+)
+
+// Redactor replaces sensitive values in conversations with placeholders that can later be reversed.
+type Redactor struct {
+	patterns []Pattern
+}
+
+// Redact returns a redacted child of the given conversation along with the mapping needed to restore the original
+// values. Each distinct value is replaced with the same placeholder wherever it appears.
+func (r Redactor) Redact(c *conversation.Conversation) (*conversation.Conversation, *Mapping) {
+	m := NewMapping()
+	return r.RedactWith(c, m), m
+}
+
+// RedactWith returns a redacted child of the given conversation, reusing and extending the given mapping. This keeps
+// placeholders stable across several turns of the same conversation.
+func (r Redactor) RedactWith(c *conversation.Conversation, m *Mapping) *conversation.Conversation {
+	messages := c.Messages()
+	redacted := make([]conversation.Message, len(messages))
+	for i, msg := range messages {
+		redacted[i] = r.redactMessage(msg, m)
+	}
+	return c.NewChild().WithMessages(redacted...)
+}
+
+// redactMessage returns a copy of the message with its content, or each of its parts, redacted.
+func (r Redactor) redactMessage(msg conversation.Message, m *Mapping) message.Message {
+	converted := message.From(msg)
+	if parts := converted.Parts(); len(parts) > 0 {
+		for i, part := range parts {
+			parts[i] = r.String(part, m)
+		}
+		return converted.WithParts(parts...)
+	}
+	return converted.WithContent(r.String(converted.Content(), m))
+}
+
+// String returns s with every sensitive value replaced by its placeholder, recording new placeholders in the mapping.
+func (r Redactor) String(s string, m *Mapping) string {
+	for _, p := range r.patterns {
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if p.Valid != nil && !p.Valid(match) {
+				return match
+			}
+			return m.placeholder(p.Name, match)
+		})
+	}
+	return s
+}
+
+// Wrap returns a completer that sends a redacted copy of each conversation to the next completer and restores the
+// original values in its reply.
+func (r Redactor) Wrap(next completion.Completer) completion.Completer {
+	return completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		redacted, m := r.Redact(c)
+		reply, err := next.Complete(ctx, redacted)
+		if err != nil {
+			return reply, err
+		}
+		return m.RestoreMessage(reply), nil
+	})
+}
+
+// WithPattern configures a redactor with an additional pattern, applied after those already configured.
+func (r Redactor) WithPattern(p Pattern) Redactor {
+	patterns := make([]Pattern, len(r.patterns), len(r.patterns)+1)
+	copy(patterns, r.patterns)
+	r.patterns = append(patterns, p)
+	return r
+}
+
+// WithPatterns configures a redactor with exactly the given patterns, replacing any already configured.
+func (r Redactor) WithPatterns(patterns ...Pattern) Redactor {
+	r.patterns = append([]Pattern(nil), patterns...)
+	return r
+}
+
+// New creates a new redactor using the CreditCard, Email and Phone patterns.
+func New() Redactor {
+	return Redactor{patterns: []Pattern{CreditCard, Email, Phone}}
+}
+
+// Mapping records the original value behind each placeholder produced by a redactor.
+type Mapping struct {
+	originals    map[string]string
+	placeholders map[string]string
+	counts       map[string]int
+}
+
+// placeholder returns the placeholder for the given value, creating one if the value has not been seen before.
+func (m *Mapping) placeholder(name, original string) string {
+	if p, ok := m.placeholders[original]; ok {
+		return p
+	}
+	m.counts[name]++
+	p := fmt.Sprintf("[%s_%d]", name, m.counts[name])
+	m.placeholders[original] = p
+	m.originals[p] = original
+	return p
+}
+
+// Original returns the original value behind a placeholder, and whether the placeholder is known.
+func (m *Mapping) Original(placeholder string) (string, bool) {
+	original, ok := m.originals[placeholder]
+	return original, ok
+}
+
+// Placeholders returns the placeholders in the mapping, sorted.
+func (m *Mapping) Placeholders() []string {
+	placeholders := make([]string, 0, len(m.originals))
+	for p := range m.originals {
+		placeholders = append(placeholders, p)
+	}
+	sort.Strings(placeholders)
+	return placeholders
+}
+
+// Len returns the number of placeholders in the mapping.
+func (m *Mapping) Len() int {
+	return len(m.originals)
+}
+
+// Restore returns s with every known placeholder replaced by its original value.
+func (m *Mapping) Restore(s string) string {
+	if len(m.originals) == 0 {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(m.originals))
+	for p, original := range m.originals {
+		pairs = append(pairs, p, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// RestoreMessage returns a copy of the message with every known placeholder in its content, or each of its parts,
+// replaced by its original value.
+func (m *Mapping) RestoreMessage(msg message.Message) message.Message {
+	if parts := msg.Parts(); len(parts) > 0 {
+		for i, part := range parts {
+			parts[i] = m.Restore(part)
+		}
+		return msg.WithParts(parts...)
+	}
+	return msg.WithContent(m.Restore(msg.Content()))
+}
+
+// NewMapping creates a new, empty mapping.
+func NewMapping() *Mapping {
+	return &Mapping{
+		originals:    make(map[string]string),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// luhn returns true if the digits in s pass the Luhn checksum used by payment card numbers.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
@@ -0,0 +1,94 @@
+package redact_test
+
+import (
+	"context"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/redact"
+	"regexp"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	t.Run("built-in patterns", func(t *testing.T) {
+		tests := []struct {
+			name string
+			in   string
+			want string
+		}{
+			{name: "email", in: "Mail jane.doe+work@example.co.uk today", want: "Mail [EMAIL_1] today"},
+			{name: "phone", in: "Call (555) 123-4567 or +1 555.987.6543", want: "Call [PHONE_1] or [PHONE_2]"},
+			{name: "credit card", in: "Card 4111 1111 1111 1111 expires soon", want: "Card [CREDIT_CARD_1] expires soon"},
+			{name: "invalid credit card", in: "Order 1234 5678 9012 3456", want: "Order 1234 5678 9012 3456"},
+			{name: "repeated value", in: "a@b.io, c@d.io, a@b.io", want: "[EMAIL_1], [EMAIL_2], [EMAIL_1]"},
+			{name: "nothing to redact", in: "Hello!", want: "Hello!"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				m := redact.NewMapping()
+				got := redact.New().String(tt.in, m)
+				if got != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, got)
+				}
+				if restored := m.Restore(got); restored != tt.in {
+					t.Errorf("expected restored string to be %q, got %q", tt.in, restored)
+				}
+			})
+		}
+	})
+	t.Run("custom patterns", func(t *testing.T) {
+		r := redact.New().WithPatterns().WithPattern(redact.Pattern{Name: "ACCOUNT", Regexp: regexp.MustCompile(`ACCT-\d+`)})
+		m := redact.NewMapping()
+		if got := r.String("ACCT-42 belongs to a@b.io", m); got != "[ACCOUNT_1] belongs to a@b.io" {
+			t.Errorf("unexpected redaction %q", got)
+		}
+		if original, ok := m.Original("[ACCOUNT_1]"); !ok || original != "ACCT-42" {
+			t.Errorf("expected placeholder to map to ACCT-42, got %q", original)
+		}
+	})
+	t.Run("conversation", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+			message.New().WithRole(message.RoleUser).WithParts("My email is ", "a@b.io").WithMetadata("id", "1"),
+		)
+		redacted, m := redact.New().Redact(c)
+		if redacted.Parent() != c {
+			t.Errorf("expected redacted conversation to be a child of the original")
+		}
+		got := redacted.Message(1).(message.Message)
+		if got.Content() != "My email is [EMAIL_1]" || len(got.Parts()) != 2 || got.Metadata()["id"] != "1" {
+			t.Errorf("unexpected message %q %v", got.Content(), got.Metadata())
+		}
+		if c.Message(1).Content() != "My email is a@b.io" {
+			t.Errorf("expected original conversation to be unchanged")
+		}
+		if m.Len() != 1 || m.Placeholders()[0] != "[EMAIL_1]" {
+			t.Errorf("unexpected placeholders %v", m.Placeholders())
+		}
+		next := redact.New().RedactWith(conversation.New().WithMessages(
+			message.New().WithRole(message.RoleUser).WithContent("Also c@d.io and a@b.io"),
+		), m)
+		if next.Message(0).Content() != "Also [EMAIL_2] and [EMAIL_1]" {
+			t.Errorf("expected placeholders to be reused, got %q", next.Message(0).Content())
+		}
+	})
+	t.Run("wrap", func(t *testing.T) {
+		var sent string
+		completer := redact.New().Wrap(completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+			sent = c.Messages().Transcript()
+			return message.New().WithRole(message.RoleAssistant).WithContent("I'll write to [EMAIL_1]."), nil
+		}))
+		c := conversation.New().WithMessages(message.New().WithRole(message.RoleUser).WithContent("Email a@b.io please."))
+		reply, err := completer.Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sent != "user: Email [EMAIL_1] please." {
+			t.Errorf("expected redacted conversation to be sent, got %q", sent)
+		}
+		if reply.Content() != "I'll write to a@b.io." {
+			t.Errorf("expected reply to be restored, got %q", reply.Content())
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"regexp"
+	"strings"
+)
+
+// Alpaca renders conversations in the Alpaca instruction format, with the system prompt as a preamble:
+//
+//	You are a helpful assistant.
+//
+//	### Instruction:
+//	Hello!
+//
+//	### Response:
+type Alpaca struct {
+	tokenizer message.Tokenizer
+}
+
+var alpacaHeader = regexp.MustCompile(`(?m)^### (Instruction|Response):[ \t]*\n?`)
+
+// Render implements the Renderer interface.
+func (r Alpaca) Render(c *conversation.Conversation) string {
+	system, ts := turns(c)
+	var b strings.Builder
+	if system != "" {
+		fmt.Fprintf(&b, "%s\n\n", system)
+	}
+	for _, t := range ts {
+		fmt.Fprintf(&b, "### Instruction:\n%s\n\n", t.user)
+		if t.answered {
+			fmt.Fprintf(&b, "### Response:\n%s\n\n", t.assistant)
+		}
+	}
+	b.WriteString("### Response:\n")
+	return b.String()
+}
+
+// Parse implements the Renderer interface.
+func (r Alpaca) Parse(prompt string) (*conversation.Conversation, error) {
+	headers := alpacaHeader.FindAllStringSubmatchIndex(prompt, -1)
+	if headers == nil && strings.TrimSpace(prompt) != "" {
+		return nil, fmt.Errorf("%w: no Alpaca instructions found", ErrMalformedPrompt)
+	}
+	b := builder{tokenizer: r.tokenizer}
+	if len(headers) > 0 {
+		b.add(message.RoleSystem, strings.TrimSpace(prompt[:headers[0][0]]))
+	}
+	for i, header := range headers {
+		end := len(prompt)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		role := message.RoleUser
+		if prompt[header[2]:header[3]] == "Response" {
+			role = message.RoleAssistant
+		}
+		b.add(role, strings.TrimSpace(prompt[header[1]:end]))
+	}
+	return b.conversation(), nil
+}
+
+// WithTokenizer configures the renderer with the tokenizer given to parsed messages.
+func (r Alpaca) WithTokenizer(t message.Tokenizer) Alpaca {
+	r.tokenizer = t
+	return r
+}
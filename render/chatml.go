@@ -0,0 +1,51 @@
+package render
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"regexp"
+	"strings"
+)
+
+// ChatML renders conversations in the ChatML format, which supports any role name:
+//
+//	<|im_start|>system
+//	You are a helpful assistant.<|im_end|>
+//	<|im_start|>user
+//	Hello!<|im_end|>
+//	<|im_start|>assistant
+type ChatML struct {
+	tokenizer message.Tokenizer
+}
+
+var chatMLMessage = regexp.MustCompile(`(?s)<\|im_start\|>([^\n]*)\n(.*?)(?:<\|im_end\|>|$)`)
+
+// Render implements the Renderer interface.
+func (r ChatML) Render(c *conversation.Conversation) string {
+	var b strings.Builder
+	for _, m := range c.Messages() {
+		fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", m.Role(), m.Content())
+	}
+	b.WriteString("<|im_start|>assistant\n")
+	return b.String()
+}
+
+// Parse implements the Renderer interface.
+func (r ChatML) Parse(prompt string) (*conversation.Conversation, error) {
+	matches := chatMLMessage.FindAllStringSubmatch(prompt, -1)
+	if matches == nil && strings.TrimSpace(prompt) != "" {
+		return nil, fmt.Errorf("%w: no ChatML messages found", ErrMalformedPrompt)
+	}
+	b := builder{tokenizer: r.tokenizer}
+	for _, match := range matches {
+		b.add(message.Role(strings.TrimSpace(match[1])), match[2])
+	}
+	return b.conversation(), nil
+}
+
+// WithTokenizer configures the renderer with the tokenizer given to parsed messages.
+func (r ChatML) WithTokenizer(t message.Tokenizer) ChatML {
+	r.tokenizer = t
+	return r
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+)
+
+// Llama2 renders conversations in the Llama 2 chat format, with the system prompt embedded in the first instruction:
+//
+//	<s>[INST] <<SYS>>
+//	You are a helpful assistant.
+//	<</SYS>>
+//
+//	Hello! [/INST] Hi there! </s><s>[INST] How are you? [/INST]
+type Llama2 struct {
+	tokenizer message.Tokenizer
+}
+
+// Render implements the Renderer interface.
+func (r Llama2) Render(c *conversation.Conversation) string {
+	system, ts := turns(c)
+	if len(ts) == 0 || ts[len(ts)-1].answered {
+		ts = append(ts, turn{})
+	}
+	var b strings.Builder
+	for i, t := range ts {
+		instruction := t.user
+		if i == 0 && system != "" {
+			instruction = fmt.Sprintf("<<SYS>>\n%s\n<</SYS>>\n\n%s", system, instruction)
+		}
+		fmt.Fprintf(&b, "<s>[INST] %s [/INST]", instruction)
+		if t.answered {
+			fmt.Fprintf(&b, " %s </s>", t.assistant)
+		}
+	}
+	return b.String()
+}
+
+// Parse implements the Renderer interface.
+func (r Llama2) Parse(prompt string) (*conversation.Conversation, error) {
+	b := builder{tokenizer: r.tokenizer}
+	for i, segment := range strings.Split(prompt, "<s>") {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+		segment = strings.TrimSpace(strings.TrimSuffix(segment, "</s>"))
+		instruction, response, ok := strings.Cut(strings.TrimPrefix(segment, "[INST]"), "[/INST]")
+		if !ok || !strings.HasPrefix(segment, "[INST]") {
+			return nil, fmt.Errorf("%w: turn %d is not enclosed in [INST] and [/INST]", ErrMalformedPrompt, i)
+		}
+		instruction = strings.TrimSpace(instruction)
+		if system, rest, ok := strings.Cut(instruction, "<</SYS>>"); ok && strings.HasPrefix(system, "<<SYS>>") {
+			b.add(message.RoleSystem, strings.TrimSpace(strings.TrimPrefix(system, "<<SYS>>")))
+			instruction = strings.TrimSpace(rest)
+		}
+		b.add(message.RoleUser, instruction)
+		b.add(message.RoleAssistant, strings.TrimSpace(response))
+	}
+	return b.conversation(), nil
+}
+
+// WithTokenizer configures the renderer with the tokenizer given to parsed messages.
+func (r Llama2) WithTokenizer(t message.Tokenizer) Llama2 {
+	r.tokenizer = t
+	return r
+}
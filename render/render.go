@@ -0,0 +1,84 @@
+package render
+
+import (
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+)
+
+// ErrMalformedPrompt is returned when a prompt cannot be parsed in a renderer's format.
+var ErrMalformedPrompt = errors.New("malformed prompt")
+
+// Renderer converts conversations to and from the single prompt string expected by models that don't accept a list
+// of messages.
+type Renderer interface {
+	// Render returns the conversation as a prompt, ending with the cue for the assistant's next response.
+	Render(c *conversation.Conversation) string
+	// Parse returns the conversation represented by a prompt.
+	Parse(prompt string) (*conversation.Conversation, error)
+}
+
+// turn is a user instruction followed by the assistant's response to it.
+type turn struct {
+	user      string
+	assistant string
+	answered  bool
+}
+
+// turns splits a conversation into its system prompt and a list of turns, for formats that only support a single
+// system prompt and strictly alternating user and assistant messages. System messages are joined into a single system
+// prompt, consecutive messages from the same role are joined with a newline, and any role other than system or
+// assistant is treated as the user.
+func turns(c *conversation.Conversation) (system string, ts []turn) {
+	var systems []string
+	var current turn
+	var pending bool
+	for _, m := range c.Messages() {
+		switch message.Role(m.Role()) {
+		case message.RoleSystem:
+			systems = append(systems, m.Content())
+		case message.RoleAssistant:
+			current.assistant = join(current.assistant, m.Content())
+			current.answered = true
+			pending = true
+		default:
+			if current.answered {
+				ts = append(ts, current)
+				current = turn{}
+			}
+			current.user = join(current.user, m.Content())
+			pending = true
+		}
+	}
+	if pending {
+		ts = append(ts, current)
+	}
+	return strings.Join(systems, "\n\n"), ts
+}
+
+// join joins two contents with a newline, omitting the newline if the first is empty.
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "\n" + b
+}
+
+// builder accumulates parsed messages into a conversation.
+type builder struct {
+	tokenizer message.Tokenizer
+	messages  []conversation.Message
+}
+
+// add appends a message unless its content is empty.
+func (b *builder) add(role message.Role, content string) {
+	if content == "" {
+		return
+	}
+	b.messages = append(b.messages, message.New().WithRole(role).WithContent(content).WithTokenizer(b.tokenizer))
+}
+
+func (b *builder) conversation() *conversation.Conversation {
+	return conversation.New().WithMessages(b.messages...)
+}
@@ -0,0 +1,94 @@
+package render_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/render"
+	"testing"
+)
+
+func TestRenderers(t *testing.T) {
+	tests := []struct {
+		name     string
+		renderer render.Renderer
+		want     string
+	}{
+		{
+			name:     "ChatML",
+			renderer: render.ChatML{},
+			want:     "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHello!<|im_end|>\n<|im_start|>assistant\nHi there!<|im_end|>\n<|im_start|>user\nHow are you?<|im_end|>\n<|im_start|>assistant\n",
+		},
+		{
+			name:     "Llama2",
+			renderer: render.Llama2{},
+			want:     "<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHello! [/INST] Hi there! </s><s>[INST] How are you? [/INST]",
+		},
+		{
+			name:     "Alpaca",
+			renderer: render.Alpaca{},
+			want:     "Be brief.\n\n### Instruction:\nHello!\n\n### Response:\nHi there!\n\n### Instruction:\nHow are you?\n\n### Response:\n",
+		},
+		{
+			name:     "Vicuna",
+			renderer: render.Vicuna{},
+			want:     "Be brief. USER: Hello! ASSISTANT: Hi there!</s>USER: How are you? ASSISTANT:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConversation()
+			got := tt.renderer.Render(c)
+			if got != tt.want {
+				t.Errorf("expected prompt to be %q, got %q", tt.want, got)
+			}
+			parsed, err := tt.renderer.Parse(got)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if parsed.Messages().Transcript() != c.Messages().Transcript() {
+				t.Errorf("expected parsed conversation to be\n%s\ngot\n%s", c.Messages().Transcript(), parsed.Messages().Transcript())
+			}
+		})
+	}
+	t.Run("merges consecutive messages", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+			message.New().WithRole(message.RoleUser).WithContent("Anyone there?"),
+		)
+		want := "<s>[INST] Hello!\nAnyone there? [/INST]"
+		if got := (render.Llama2{}).Render(c); got != want {
+			t.Errorf("expected prompt to be %q, got %q", want, got)
+		}
+	})
+	t.Run("malformed", func(t *testing.T) {
+		for _, r := range []render.Renderer{render.ChatML{}, render.Llama2{}, render.Alpaca{}, render.Vicuna{}} {
+			if _, err := r.Parse("not a prompt"); !errors.Is(err, render.ErrMalformedPrompt) {
+				t.Errorf("expected %T to return ErrMalformedPrompt, got %v", r, err)
+			}
+		}
+	})
+	t.Run("tokenizer", func(t *testing.T) {
+		tokenizer := message.TokenizerFunc(func(s string) ([]int, error) { return []int{0}, nil })
+		parsed, err := render.ChatML{}.WithTokenizer(tokenizer).Parse(render.ChatML{}.Render(testConversation()))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		count, err := parsed.CountTokens()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if count != 4 {
+			t.Errorf("expected 4 tokens, got %d", count)
+		}
+	})
+}
+
+func testConversation() *conversation.Conversation {
+	return conversation.New().WithMessages(
+		message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+		message.New().WithRole(message.RoleUser).WithContent("Hello!"),
+		message.New().WithRole(message.RoleAssistant).WithContent("Hi there!"),
+		message.New().WithRole(message.RoleUser).WithContent("How are you?"),
+	)
+}
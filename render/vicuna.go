@@ -0,0 +1,68 @@
+package render
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"regexp"
+	"strings"
+)
+
+// Vicuna renders conversations in the Vicuna v1.1 format, with the system prompt as a preamble:
+//
+//	You are a helpful assistant. USER: Hello! ASSISTANT: Hi there!</s>USER: How are you? ASSISTANT:
+//
+// Because the format has no delimiters other than the role labels, messages containing "USER:" or "ASSISTANT:" will
+// not survive a round trip.
+type Vicuna struct {
+	tokenizer message.Tokenizer
+}
+
+var vicunaLabel = regexp.MustCompile(`(USER|ASSISTANT):`)
+
+// Render implements the Renderer interface.
+func (r Vicuna) Render(c *conversation.Conversation) string {
+	system, ts := turns(c)
+	var b strings.Builder
+	if system != "" {
+		fmt.Fprintf(&b, "%s ", system)
+	}
+	for _, t := range ts {
+		fmt.Fprintf(&b, "USER: %s ", t.user)
+		if t.answered {
+			fmt.Fprintf(&b, "ASSISTANT: %s</s>", t.assistant)
+		}
+	}
+	b.WriteString("ASSISTANT:")
+	return b.String()
+}
+
+// Parse implements the Renderer interface.
+func (r Vicuna) Parse(prompt string) (*conversation.Conversation, error) {
+	labels := vicunaLabel.FindAllStringSubmatchIndex(prompt, -1)
+	if labels == nil && strings.TrimSpace(prompt) != "" {
+		return nil, fmt.Errorf("%w: no Vicuna turns found", ErrMalformedPrompt)
+	}
+	b := builder{tokenizer: r.tokenizer}
+	if len(labels) > 0 {
+		b.add(message.RoleSystem, strings.TrimSpace(prompt[:labels[0][0]]))
+	}
+	for i, label := range labels {
+		end := len(prompt)
+		if i+1 < len(labels) {
+			end = labels[i+1][0]
+		}
+		role := message.RoleUser
+		if prompt[label[2]:label[3]] == "ASSISTANT" {
+			role = message.RoleAssistant
+		}
+		b.add(role, strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(prompt[label[1]:end]), "</s>")))
+	}
+	return b.conversation(), nil
+}
+
+// WithTokenizer configures the renderer with the tokenizer given to parsed messages.
+func (r Vicuna) WithTokenizer(t message.Tokenizer) Vicuna {
+	r.tokenizer = t
+	return r
+}
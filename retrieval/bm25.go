@@ -0,0 +1,150 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Document is a piece of text added to an Index.
+type Document struct {
+	ID       string
+	Content  string
+	Metadata map[string]string
+}
+
+// Default BM25 parameters, used unless an index is configured with WithParameters.
+const (
+	DefaultK1 = 1.2
+	DefaultB  = 0.75
+)
+
+// Index is an in-memory retriever that ranks documents against a query using the BM25 algorithm. It is safe for
+// concurrent use. The zero value is an empty index ready to use.
+type Index struct {
+	documents  []Document
+	terms      []map[string]int
+	lengths    []int
+	frequency  map[string]int
+	total      int
+	k1         float64
+	b          float64
+	configured bool
+	mutex      sync.RWMutex
+}
+
+// Add adds documents to the index.
+func (x *Index) Add(documents ...Document) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.init()
+	for _, d := range documents {
+		terms := make(map[string]int)
+		words := tokenize(d.Content)
+		for _, w := range words {
+			terms[w]++
+		}
+		for w := range terms {
+			x.frequency[w]++
+		}
+		x.documents = append(x.documents, d)
+		x.terms = append(x.terms, terms)
+		x.lengths = append(x.lengths, len(words))
+		x.total += len(words)
+	}
+}
+
+// Len returns the number of documents in the index.
+func (x *Index) Len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return len(x.documents)
+}
+
+// Retrieve returns up to limit documents that share at least one term with the query, highest scoring first.
+// Documents with equal scores are returned in the order they were added. A limit of zero or less returns every match.
+func (x *Index) Retrieve(ctx context.Context, query string, limit int) ([]Passage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	if len(x.documents) == 0 {
+		return nil, nil
+	}
+	n := float64(len(x.documents))
+	average := float64(x.total) / n
+	k1, b := x.k1, x.b
+	if !x.configured {
+		k1, b = DefaultK1, DefaultB
+	}
+	var passages []Passage
+	for i, d := range x.documents {
+		var score float64
+		seen := make(map[string]bool)
+		for _, w := range tokenize(query) {
+			if seen[w] {
+				continue
+			}
+			seen[w] = true
+			tf := float64(x.terms[i][w])
+			if tf == 0 {
+				continue
+			}
+			df := float64(x.frequency[w])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(x.lengths[i])/average))
+		}
+		if score > 0 {
+			passages = append(passages, Passage{ID: d.ID, Content: d.Content, Score: score, Metadata: copyMetadata(d.Metadata)})
+		}
+	}
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].Score > passages[j].Score })
+	if limit > 0 && len(passages) > limit {
+		passages = passages[:limit]
+	}
+	return passages, nil
+}
+
+// WithParameters configures an index with the BM25 k1 and b parameters, which control term frequency saturation and
+// document length normalization. The defaults are DefaultK1 and DefaultB.
+func (x *Index) WithParameters(k1, b float64) *Index {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.k1, x.b, x.configured = k1, b, true
+	return x
+}
+
+// init initializes the index.
+func (x *Index) init() {
+	if x.frequency == nil {
+		x.frequency = make(map[string]int)
+	}
+}
+
+// NewIndex creates a new, empty index.
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// tokenize splits text into lowercase words made of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// copyMetadata returns a copy of the metadata, or nil if it is empty.
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,222 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+)
+
+// MetadataCitations is the metadata key holding the IDs of the passages injected into a conversation, as a JSON array.
+const MetadataCitations = "citations"
+
+// DefaultBudget is the token budget of the context message once an injector has a tokenizer, unless another budget is
+// configured.
+const DefaultBudget = 1024
+
+// DefaultHeader introduces the injected passages when no other header is configured.
+const DefaultHeader = "Use the following passages to answer the user's question. Cite passages by their number, such as [1], when you use them."
+
+// Passage is a piece of text returned by a retriever.
+type Passage struct {
+	ID       string
+	Content  string
+	Score    float64
+	Metadata map[string]string
+}
+
+// Retriever finds passages relevant to a query.
+type Retriever interface {
+	// Retrieve returns up to limit passages relevant to the query, most relevant first.
+	Retrieve(ctx context.Context, query string, limit int) ([]Passage, error)
+}
+
+// RetrieverFunc wraps a function as a retriever.
+type RetrieverFunc func(ctx context.Context, query string, limit int) ([]Passage, error)
+
+// Retrieve calls the wrapped function.
+func (f RetrieverFunc) Retrieve(ctx context.Context, query string, limit int) ([]Passage, error) {
+	return f(ctx, query, limit)
+}
+
+// Injector adds passages relevant to the latest user message to a conversation.
+type Injector struct {
+	retriever Retriever
+	tokenizer message.Tokenizer
+	budget    int
+	budgeted  bool
+	limit     int
+	role      message.Role
+	header    string
+}
+
+// Inject returns a child of the given conversation with a context message inserted just before the latest user
+// message. The context message lists the passages retrieved for the user message, most relevant first, and records
+// their IDs in its MetadataCitations metadata. Passages are skipped if including them would exceed the token budget.
+// If the conversation has no user message, or no passages fit, the original conversation is returned unchanged.
+// ErrNoTokenizer is returned if a budget was configured with WithBudget but no tokenizer was.
+func (i Injector) Inject(ctx context.Context, c *conversation.Conversation) (*conversation.Conversation, error) {
+	if i.budgeted && i.budget > 0 && i.tokenizer == nil {
+		return nil, message.ErrNoTokenizer
+	}
+	messages := c.Messages()
+	index := -1
+	for j := len(messages) - 1; j >= 0; j-- {
+		if messages[j].Role() == string(message.RoleUser) {
+			index = j
+			break
+		}
+	}
+	if index < 0 {
+		return c, nil
+	}
+	passages, err := i.retriever.Retrieve(ctx, messages[index].Content(), i.limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve passages: %w", err)
+	}
+	var included []Passage
+	for _, p := range passages {
+		fits, err := i.fits(append(included, p))
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			included = append(included, p)
+		}
+	}
+	if len(included) == 0 {
+		return c, nil
+	}
+	m, err := i.message(included)
+	if err != nil {
+		return nil, err
+	}
+	injected := make([]conversation.Message, 0, len(messages)+1)
+	injected = append(injected, messages[:index]...)
+	injected = append(injected, m)
+	injected = append(injected, messages[index:]...)
+	return c.NewChild().WithMessages(injected...), nil
+}
+
+// Wrap returns a completer that injects passages into each conversation before passing it to the next completer.
+// The reply's MetadataCitations metadata holds the IDs of the injected passages.
+func (i Injector) Wrap(next completion.Completer) completion.Completer {
+	return completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+		injected, err := i.Inject(ctx, c)
+		if err != nil {
+			return message.Message{}, err
+		}
+		reply, err := next.Complete(ctx, injected)
+		if err != nil || injected == c {
+			return reply, err
+		}
+		messages := injected.Messages()
+		for j := len(messages) - 1; j >= 0; j-- {
+			md, ok := messages[j].(interface{ Metadata() map[string]string })
+			if !ok {
+				continue
+			}
+			if citations, ok := md.Metadata()[MetadataCitations]; ok {
+				return reply.WithMetadata(MetadataCitations, citations), nil
+			}
+		}
+		return reply, nil
+	})
+}
+
+// fits returns true if a context message listing the passages fits within the token budget.
+func (i Injector) fits(passages []Passage) (bool, error) {
+	if i.budget <= 0 || i.tokenizer == nil {
+		return true, nil
+	}
+	tokens, err := i.tokenizer.Tokenize(i.content(passages))
+	if err != nil {
+		return false, fmt.Errorf("could not tokenize passages: %w", err)
+	}
+	return len(tokens) <= i.budget, nil
+}
+
+// message returns the context message listing the passages.
+func (i Injector) message(passages []Passage) (message.Message, error) {
+	ids := make([]string, len(passages))
+	for j, p := range passages {
+		ids[j] = p.ID
+	}
+	citations, err := json.Marshal(ids)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("could not marshal citations: %w", err)
+	}
+	m := message.New().WithRole(i.role).WithContent(i.content(passages)).WithMetadata(MetadataCitations, string(citations))
+	if i.tokenizer != nil {
+		m = m.WithTokenizer(i.tokenizer)
+	}
+	return m, nil
+}
+
+// content returns the header followed by the numbered passages.
+func (i Injector) content(passages []Passage) string {
+	var b strings.Builder
+	b.WriteString(i.header)
+	for j, p := range passages {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%d] %s", j+1, p.Content)
+	}
+	return b.String()
+}
+
+// WithTokenizer configures an injector with the tokenizer used to enforce its token budget. The tokenizer is also
+// given to the context message. Without a tokenizer, the default budget is not enforced.
+func (i Injector) WithTokenizer(t message.Tokenizer) Injector {
+	i.tokenizer = t
+	return i
+}
+
+// WithBudget configures an injector with the maximum number of tokens in the context message, including its header.
+// A budget of zero or less disables the limit. The default is DefaultBudget, which only applies once a tokenizer is
+// configured; a budget configured with WithBudget requires a tokenizer.
+func (i Injector) WithBudget(tokens int) Injector {
+	i.budget = tokens
+	i.budgeted = true
+	return i
+}
+
+// WithLimit configures an injector with the maximum number of passages requested from the retriever. The default is 5.
+func (i Injector) WithLimit(n int) Injector {
+	i.limit = n
+	return i
+}
+
+// WithRole configures an injector with the role of the context message. The default is the system role.
+func (i Injector) WithRole(role message.Role) Injector {
+	i.role = role
+	return i
+}
+
+// WithHeader configures an injector with the text placed before the passages in the context message.
+func (i Injector) WithHeader(header string) Injector {
+	i.header = header
+	return i
+}
+
+// New creates a new injector that retrieves passages from the given retriever.
+func New(r Retriever) Injector {
+	return Injector{retriever: r, budget: DefaultBudget, limit: 5, role: message.RoleSystem, header: DefaultHeader}
+}
+
+// Citations returns the IDs of the passages recorded in a message's MetadataCitations metadata, if any.
+func Citations(m conversation.Message) []string {
+	md, ok := m.(interface{ Metadata() map[string]string })
+	if !ok {
+		return nil
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(md.Metadata()[MetadataCitations]), &ids); err != nil {
+		return nil
+	}
+	return ids
+}
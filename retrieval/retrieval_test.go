@@ -0,0 +1,159 @@
+package retrieval_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/retrieval"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	index := retrieval.NewIndex()
+	index.Add(
+		retrieval.Document{ID: "cats", Content: "Cats sleep most of the day.", Metadata: map[string]string{"source": "pets.txt"}},
+		retrieval.Document{ID: "dogs", Content: "Dogs need a walk every day, and dogs love to play."},
+		retrieval.Document{ID: "fish", Content: "Fish live in water."},
+	)
+	t.Run("ranks matches", func(t *testing.T) {
+		passages, err := index.Retrieve(context.Background(), "How often do dogs need a walk?", 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(passages) != 1 || passages[0].ID != "dogs" || passages[0].Score <= 0 {
+			t.Errorf("unexpected passages %+v", passages)
+		}
+		passages, _ = index.Retrieve(context.Background(), "DAY", 0)
+		var ids []string
+		for _, p := range passages {
+			ids = append(ids, p.ID)
+		}
+		if !reflect.DeepEqual(ids, []string{"cats", "dogs"}) {
+			t.Errorf("expected the shorter document to rank first, got %v", ids)
+		}
+		if passages[0].Metadata["source"] != "pets.txt" {
+			t.Errorf("expected metadata to be returned, got %v", passages[0].Metadata)
+		}
+	})
+	t.Run("limit", func(t *testing.T) {
+		passages, _ := index.Retrieve(context.Background(), "day water", 2)
+		if len(passages) != 2 || index.Len() != 3 {
+			t.Errorf("expected 2 of 3 documents, got %d of %d", len(passages), index.Len())
+		}
+	})
+	t.Run("no matches", func(t *testing.T) {
+		if passages, _ := index.Retrieve(context.Background(), "birds", 5); len(passages) != 0 {
+			t.Errorf("expected no passages, got %+v", passages)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := index.Retrieve(ctx, "cats", 5); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestZeroIndex(t *testing.T) {
+	var index retrieval.Index
+	index.Add(retrieval.Document{ID: "cats", Content: "Cats sleep."}, retrieval.Document{ID: "dogs", Content: "Dogs play."})
+	passages, err := index.Retrieve(context.Background(), "cats", 0)
+	if err != nil || len(passages) != 1 || passages[0].ID != "cats" {
+		t.Errorf("expected the zero index to be usable, got %+v and %v", passages, err)
+	}
+	configured := retrieval.NewIndex().WithParameters(retrieval.DefaultK1, retrieval.DefaultB)
+	configured.Add(retrieval.Document{ID: "cats", Content: "Cats sleep."}, retrieval.Document{ID: "dogs", Content: "Dogs play."})
+	if want, _ := configured.Retrieve(context.Background(), "cats", 0); want[0].Score != passages[0].Score {
+		t.Errorf("expected the default parameters to be used, got score %v instead of %v", passages[0].Score, want[0].Score)
+	}
+}
+
+func TestInjector(t *testing.T) {
+	retriever := retrieval.RetrieverFunc(func(ctx context.Context, query string, limit int) ([]retrieval.Passage, error) {
+		if query != "Where is the office?" {
+			t.Errorf("expected the latest user message to be the query, got %q", query)
+		}
+		return []retrieval.Passage{
+			{ID: "a", Content: "The office is in Paris."},
+			{ID: "b", Content: "The office has a large garden with many old trees and a pond."},
+			{ID: "c", Content: "It opens at nine."},
+		}, nil
+	})
+	c := conversation.New().WithMessages(
+		message.New().WithRole(message.RoleSystem).WithContent("Be brief."),
+		message.New().WithRole(message.RoleUser).WithContent("Hi!"),
+		message.New().WithRole(message.RoleAssistant).WithContent("Hello!"),
+		message.New().WithRole(message.RoleUser).WithContent("Where is the office?"),
+	)
+	t.Run("within budget", func(t *testing.T) {
+		injector := retrieval.New(retriever).WithTokenizer(testTokenizer).WithBudget(12).WithHeader("Context:")
+		injected, err := injector.Inject(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if injected.Parent() != c || c.Messages().Len() != 4 || injected.Messages().Len() != 5 {
+			t.Fatalf("expected a child conversation with one more message")
+		}
+		m := injected.Message(3)
+		if m.Role() != "system" || m.Content() != "Context:\n\n[1] The office is in Paris.\n\n[2] It opens at nine." {
+			t.Errorf("unexpected context message %s: %q", m.Role(), m.Content())
+		}
+		if got := retrieval.Citations(m); !reflect.DeepEqual(got, []string{"a", "c"}) {
+			t.Errorf("expected citations [a c], got %v", got)
+		}
+		if injected.Message(4).Content() != "Where is the office?" {
+			t.Errorf("expected the context message to precede the latest user message")
+		}
+	})
+	t.Run("nothing fits", func(t *testing.T) {
+		injected, err := retrieval.New(retriever).WithTokenizer(testTokenizer).WithBudget(3).Inject(context.Background(), c)
+		if err != nil || injected != c {
+			t.Errorf("expected the original conversation, got %v", err)
+		}
+	})
+	t.Run("no tokenizer", func(t *testing.T) {
+		if _, err := retrieval.New(retriever).WithBudget(100).Inject(context.Background(), c); !errors.Is(err, message.ErrNoTokenizer) {
+			t.Errorf("expected ErrNoTokenizer, got %v", err)
+		}
+		injected, err := retrieval.New(retriever).Inject(context.Background(), c)
+		if err != nil || injected.Messages().Len() != 5 {
+			t.Errorf("expected the default budget not to apply without a tokenizer, got %v", err)
+		}
+		if _, err := retrieval.New(retriever).WithBudget(0).Inject(context.Background(), c); err != nil {
+			t.Errorf("expected no error without a budget, got %v", err)
+		}
+	})
+	t.Run("no user message", func(t *testing.T) {
+		empty := conversation.New().WithMessages(message.New().WithRole(message.RoleSystem).WithContent("Be brief."))
+		if injected, err := retrieval.New(retriever).WithBudget(0).Inject(context.Background(), empty); err != nil || injected != empty {
+			t.Errorf("expected the original conversation, got %v", err)
+		}
+	})
+	t.Run("wrap", func(t *testing.T) {
+		var sent *conversation.Conversation
+		completer := retrieval.New(retriever).WithBudget(0).Wrap(completion.CompleterFunc(func(ctx context.Context, c *conversation.Conversation) (message.Message, error) {
+			sent = c
+			return message.New().WithRole(message.RoleAssistant).WithContent("Paris [1]."), nil
+		}))
+		reply, err := completer.Complete(context.Background(), c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sent.Messages().Len() != 5 {
+			t.Errorf("expected passages to be injected before completing")
+		}
+		if got := retrieval.Citations(reply); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+			t.Errorf("expected reply to carry citations, got %v", got)
+		}
+	})
+}
+
+// testTokenizer treats each space-separated word as a token.
+var testTokenizer = message.TokenizerFunc(func(s string) ([]int, error) {
+	return make([]int, len(strings.Fields(s))), nil
+})
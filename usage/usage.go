@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+)
+
+// Report is the token usage and dollar cost of a conversation and its children.
+type Report struct {
+	// Usage is the token usage recorded on the conversation's own messages.
+	Usage completion.Usage `json:"usage"`
+	// Cost is the dollar cost recorded on the conversation's own messages.
+	Cost float64 `json:"cost"`
+	// Completions is the number of the conversation's own messages with usage or cost recorded.
+	Completions int `json:"completions"`
+	// Children are the reports of the conversations created from this one with Fork.
+	Children []Report `json:"children,omitempty"`
+}
+
+// Total returns the usage, cost and number of completions of the conversation and all of its descendants.
+func (r Report) Total() Report {
+	total := Report{Usage: r.Usage, Cost: r.Cost, Completions: r.Completions}
+	for _, child := range r.Children {
+		t := child.Total()
+		total.Usage.PromptTokens += t.Usage.PromptTokens
+		total.Usage.CompletionTokens += t.Usage.CompletionTokens
+		total.Cost += t.Cost
+		total.Completions += t.Completions
+	}
+	return total
+}
+
+// New returns the report for a conversation and its children, using the usage and cost recorded on each message by
+// completers and completion.Meter.
+//
+// Only children created with Fork are included, since those are the only children a conversation keeps track of.
+// Temporary children created with NewChild, such as summary requests, are not; report on them separately with New, or
+// create them with Fork to include them.
+//
+// Each completion is only counted in the conversation that appended it. Children commonly start with copies of their
+// parent's messages, so the leading messages a conversation shares with its parent are not counted, and a message is
+// not counted again if a message with the same completion.MetadataCompletionID was already counted. Replies served
+// from a completion.Cache are not counted.
+func New(c *conversation.Conversation) Report {
+	return report(c, map[string]bool{})
+}
+
+func report(c *conversation.Conversation, seen map[string]bool) Report {
+	var r Report
+	messages := c.Messages()
+	for _, m := range messages[shared(c):] {
+		usage, cost := completion.UsageOf(m), completion.CostOf(m)
+		if usage == (completion.Usage{}) && cost == 0 {
+			continue
+		}
+		metadata := metadataOf(m)
+		if metadata[completion.MetadataCache] == "hit" {
+			continue
+		}
+		if id := metadata[completion.MetadataCompletionID]; id != "" {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		r.Usage.PromptTokens += usage.PromptTokens
+		r.Usage.CompletionTokens += usage.CompletionTokens
+		r.Cost += cost
+		r.Completions++
+	}
+	for _, child := range c.Children() {
+		r.Children = append(r.Children, report(child, seen))
+	}
+	return r
+}
+
+// shared returns the number of leading messages a conversation shares with its parent. Messages are shared if their
+// role, content and completion ID are the same, so separate completions with the same content are told apart.
+func shared(c *conversation.Conversation) int {
+	if c.Parent() == nil {
+		return 0
+	}
+	messages, parent := c.Messages(), c.Parent().Messages()
+	i := 0
+	for i < len(messages) && i < len(parent) && same(messages[i], parent[i]) {
+		i++
+	}
+	return i
+}
+
+// same returns true if two messages have the same role, content and completion ID.
+func same(a, b conversation.Message) bool {
+	return a.Role() == b.Role() && a.Content() == b.Content() &&
+		metadataOf(a)[completion.MetadataCompletionID] == metadataOf(b)[completion.MetadataCompletionID]
+}
+
+// metadataOf returns the message's metadata, or nil if it has none.
+func metadataOf(m conversation.Message) map[string]string {
+	if md, ok := m.(interface{ Metadata() map[string]string }); ok {
+		return md.Metadata()
+	}
+	return nil
+}
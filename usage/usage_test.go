@@ -0,0 +1,127 @@
+package usage_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/bradfair/chat/completion"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/usage"
+	"math"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	meter := completion.NewMeter().
+		WithPrice("small", completion.Price{PromptPer1K: 1, CompletionPer1K: 2}).
+		WithDefaultPrice(completion.Price{PromptPer1K: 10, CompletionPer1K: 20})
+	reply := func(model string, prompt, completionTokens int) completion.Completer {
+		return completionFunc(func() message.Message {
+			m := message.New().WithRole("assistant").WithContent(model+" reply").WithMetadata("model", model)
+			return withUsage(m, prompt, completionTokens)
+		})
+	}
+
+	c := conversation.New()
+	c.Append(message.New().WithRole("user").WithContent("hello"))
+	m, err := meter.Wrap(reply("small", 1000, 500)).Complete(context.Background(), c)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cost := completion.CostOf(m); math.Abs(cost-2) > 1e-9 {
+		t.Errorf("expected cost to be 2, got %v", cost)
+	}
+	c.Append(m)
+
+	child := c.Fork().WithMessages(c.Messages()...)
+	m, _ = meter.Wrap(reply("large", 2000, 100)).Complete(context.Background(), child)
+	child.Append(m)
+
+	r := usage.New(c)
+	if r.Completions != 1 || r.Usage.PromptTokens != 1000 || math.Abs(r.Cost-2) > 1e-9 {
+		t.Errorf("unexpected parent report %+v", r)
+	}
+	if len(r.Children) != 1 || r.Children[0].Completions != 1 {
+		t.Fatalf("expected the child's copy of the parent's reply not to be counted again, got %+v", r.Children)
+	}
+	total := r.Total()
+	if total.Completions != 2 || total.Usage.TotalTokens() != 3600 || math.Abs(total.Cost-24) > 1e-9 {
+		t.Errorf("unexpected total %+v", total)
+	}
+	if _, err := json.Marshal(r); err != nil {
+		t.Errorf("expected report to marshal, got %v", err)
+	}
+}
+
+func TestReportIdentity(t *testing.T) {
+	meter := completion.NewMeter().WithDefaultPrice(completion.Price{PromptPer1K: 1, CompletionPer1K: 2})
+	var calls int
+	upstream := completionFunc(func() message.Message {
+		calls++
+		return withUsage(message.New().WithRole("assistant").WithContent("Hi!"), 1000, 500)
+	})
+	t.Run("identical completions", func(t *testing.T) {
+		c := conversation.New()
+		for i := 0; i < 2; i++ {
+			c.Append(message.New().WithRole("user").WithContent("hello"))
+			m, _ := meter.Wrap(upstream).Complete(context.Background(), c)
+			c.Append(m)
+		}
+		if r := usage.New(c); r.Completions != 2 || math.Abs(r.Cost-4) > 1e-9 {
+			t.Errorf("expected both completions to be counted, got %+v", r)
+		}
+	})
+	t.Run("fork without copies", func(t *testing.T) {
+		c := conversation.New()
+		for _, content := range []string{"hello", "hi", "how are you?"} {
+			c.Append(message.New().WithRole("user").WithContent(content))
+		}
+		fork := c.Fork()
+		m, _ := meter.Wrap(upstream).Complete(context.Background(), c)
+		fork.Append(m)
+		if total := usage.New(c).Total(); total.Completions != 1 || math.Abs(total.Cost-2) > 1e-9 {
+			t.Errorf("expected the fork's own reply to be counted, got %+v", total)
+		}
+	})
+	t.Run("temporary children", func(t *testing.T) {
+		c := conversation.New()
+		c.Append(message.New().WithRole("user").WithContent("hello"))
+		child := c.NewChild().WithMessages(c.Messages()...)
+		m, _ := meter.Wrap(upstream).Complete(context.Background(), child)
+		child.Append(m)
+		if r := usage.New(c); r.Completions != 0 || len(r.Children) != 0 {
+			t.Errorf("expected children created with NewChild not to be included, got %+v", r)
+		}
+		if r := usage.New(child); r.Completions != 1 {
+			t.Errorf("expected a report on the child to count its reply, got %+v", r)
+		}
+	})
+	t.Run("cache hits", func(t *testing.T) {
+		calls = 0
+		completer := completion.Chain(upstream, meter.Wrap, completion.NewCache(completion.NewMemoryStore(10)).Wrap)
+		c := conversation.New()
+		c.Append(message.New().WithRole("user").WithContent("hello"))
+		for i := 0; i < 3; i++ {
+			fork := c.Fork().WithMessages(c.Messages()...)
+			m, err := completer.Complete(context.Background(), fork)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			fork.Append(m)
+		}
+		total := usage.New(c).Total()
+		if calls != 1 || total.Completions != 1 || math.Abs(total.Cost-2) > 1e-9 {
+			t.Errorf("expected 1 call billed once, got %d calls and %+v", calls, total)
+		}
+	})
+}
+
+func completionFunc(reply func() message.Message) completion.Completer {
+	return completion.CompleterFunc(func(context.Context, *conversation.Conversation) (message.Message, error) {
+		return reply(), nil
+	})
+}
+
+func withUsage(m message.Message, prompt, completionTokens int) message.Message {
+	return completion.WithUsage(m, completion.Usage{PromptTokens: prompt, CompletionTokens: completionTokens})
+}
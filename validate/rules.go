@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"strings"
+)
+
+// RoleTool is the role of messages holding the result of a tool call.
+const RoleTool = "tool"
+
+// MetadataToolCallID is the metadata key holding the ID of a tool call, on both the assistant message that made the
+// call and the tool message holding its result.
+const MetadataToolCallID = "tool_call_id"
+
+// NonEmptyContent requires every message to have content other than whitespace.
+func NonEmptyContent(messages conversation.Messages) []Violation {
+	var violations []Violation
+	for i, m := range messages {
+		if strings.TrimSpace(m.Content()) == "" {
+			violations = append(violations, Violation{Index: i, Rule: "non-empty-content", Description: "content is empty"})
+		}
+	}
+	return violations
+}
+
+// KnownRoles returns a rule that requires every message to be from one of the given roles.
+func KnownRoles(roles ...string) Rule {
+	known := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		known[role] = true
+	}
+	return func(messages conversation.Messages) []Violation {
+		var violations []Violation
+		for i, m := range messages {
+			if !known[m.Role()] {
+				violations = append(violations, Violation{Index: i, Rule: "known-roles", Description: fmt.Sprintf("unknown role %q", m.Role())})
+			}
+		}
+		return violations
+	}
+}
+
+// RegisteredRoles returns a rule that requires every message to be from a role registered in the registry.
+func RegisteredRoles(r *message.Registry) Rule {
+	return func(messages conversation.Messages) []Violation {
+		var violations []Violation
+		for i, m := range messages {
+			if !r.IsRegistered(message.Role(m.Role())) {
+				violations = append(violations, Violation{Index: i, Rule: "registered-roles", Description: fmt.Sprintf("unregistered role %q", m.Role())})
+			}
+		}
+		return violations
+	}
+}
+
+// ToolResultsFollowCalls requires every tool message to follow an assistant message or another tool message. If a tool
+// message has a tool call ID in its metadata, an earlier assistant message must have the same ID.
+func ToolResultsFollowCalls(messages conversation.Messages) []Violation {
+	var violations []Violation
+	calls := map[string]bool{}
+	for i, m := range messages {
+		id := metadata(m)[MetadataToolCallID]
+		switch m.Role() {
+		case string(message.RoleAssistant):
+			if id != "" {
+				calls[id] = true
+			}
+		case RoleTool:
+			if i == 0 || (messages[i-1].Role() != string(message.RoleAssistant) && messages[i-1].Role() != RoleTool) {
+				violations = append(violations, Violation{Index: i, Rule: "tool-results-follow-calls", Description: "tool result does not follow an assistant message"})
+			} else if id != "" && !calls[id] {
+				violations = append(violations, Violation{Index: i, Rule: "tool-results-follow-calls", Description: fmt.Sprintf("tool result for unknown call %q", id)})
+			}
+		}
+	}
+	return violations
+}
+
+// Alternating requires user and assistant messages to alternate, ignoring system messages.
+func Alternating(messages conversation.Messages) []Violation {
+	var violations []Violation
+	previous := ""
+	for i, m := range messages {
+		if m.Role() == string(message.RoleSystem) {
+			continue
+		}
+		if m.Role() == previous {
+			violations = append(violations, Violation{Index: i, Rule: "alternating", Description: fmt.Sprintf("consecutive %s messages", m.Role())})
+		}
+		previous = m.Role()
+	}
+	return violations
+}
+
+// StartsWithUser requires the first message other than system messages to be from the user.
+func StartsWithUser(messages conversation.Messages) []Violation {
+	for i, m := range messages {
+		if m.Role() == string(message.RoleSystem) {
+			continue
+		}
+		if m.Role() != string(message.RoleUser) {
+			return []Violation{{Index: i, Rule: "starts-with-user", Description: "first message is not from the user"}}
+		}
+		return nil
+	}
+	return []Violation{{Index: -1, Rule: "starts-with-user", Description: "conversation has no user messages"}}
+}
+
+// SystemFirst requires system messages to come before any other messages.
+func SystemFirst(messages conversation.Messages) []Violation {
+	var violations []Violation
+	leading := true
+	for i, m := range messages {
+		if m.Role() != string(message.RoleSystem) {
+			leading = false
+			continue
+		}
+		if !leading {
+			violations = append(violations, Violation{Index: i, Rule: "system-first", Description: "system message after other messages"})
+		}
+	}
+	return violations
+}
+
+// OpenAI is the rule set for OpenAI's chat API.
+var OpenAI = []Rule{
+	NonEmptyContent,
+	KnownRoles(string(message.RoleSystem), string(message.RoleUser), string(message.RoleAssistant), RoleTool),
+	ToolResultsFollowCalls,
+}
+
+// Anthropic is the rule set for Claude-style Messages APIs. System messages are allowed anywhere, since they are
+// hoisted into the system prompt, and consecutive messages from the same role are allowed, since the anthropic package
+// merges them before sending.
+var Anthropic = []Rule{
+	NonEmptyContent,
+	KnownRoles(string(message.RoleSystem), string(message.RoleUser), string(message.RoleAssistant)),
+	StartsWithUser,
+}
+
+// Gemini is the rule set for Gemini-style APIs. System messages are allowed anywhere, since the gemini package moves
+// them into the system instruction.
+var Gemini = []Rule{
+	NonEmptyContent,
+	KnownRoles(string(message.RoleSystem), string(message.RoleUser), string(message.RoleAssistant)),
+	Alternating,
+}
+
+// metadata returns a message's metadata, or nil if it doesn't provide a Metadata() map[string]string method.
+func metadata(m conversation.Message) map[string]string {
+	if md, ok := m.(interface{ Metadata() map[string]string }); ok {
+		return md.Metadata()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/bradfair/chat/conversation"
+	"strings"
+)
+
+// Violation is a way in which a conversation breaks a rule.
+type Violation struct {
+	// Index is the index of the offending message, or -1 if the violation applies to the conversation as a whole.
+	Index int
+	// Rule is the name of the rule that was broken.
+	Rule string
+	// Description describes the violation.
+	Description string
+}
+
+// String returns the violation as a string.
+func (v Violation) String() string {
+	if v.Index < 0 {
+		return fmt.Sprintf("%s (%s)", v.Description, v.Rule)
+	}
+	return fmt.Sprintf("message %d: %s (%s)", v.Index, v.Description, v.Rule)
+}
+
+// Violations is the error returned when a conversation breaks one or more rules.
+type Violations []Violation
+
+// Error implements the error interface.
+func (v Violations) Error() string {
+	lines := make([]string, len(v))
+	for i, violation := range v {
+		lines[i] = violation.String()
+	}
+	return "invalid conversation: " + strings.Join(lines, "; ")
+}
+
+// Rule checks a conversation's messages, returning any violations.
+type Rule func(messages conversation.Messages) []Violation
+
+// Validator checks conversations against a set of rules.
+type Validator struct {
+	rules []Rule
+}
+
+// Validate checks a conversation against every rule. If any rule is broken, all violations are returned as
+// Violations, ordered by rule and then by message index. Otherwise nil is returned.
+func (v Validator) Validate(c *conversation.Conversation) error {
+	messages := c.Messages()
+	var violations Violations
+	for _, rule := range v.rules {
+		violations = append(violations, rule(messages)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// WithRules configures a validator with additional rules.
+func (v Validator) WithRules(rules ...Rule) Validator {
+	v.rules = append(append([]Rule(nil), v.rules...), rules...)
+	return v
+}
+
+// New creates a new validator with the given rules, such as one of the provider rule sets.
+func New(rules ...Rule) Validator {
+	v := Validator{}
+	return v.WithRules(rules...)
+}
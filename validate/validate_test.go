@@ -0,0 +1,104 @@
+package validate_test
+
+import (
+	"errors"
+	"github.com/bradfair/chat/conversation"
+	"github.com/bradfair/chat/message"
+	"github.com/bradfair/chat/validate"
+	"reflect"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("system").WithContent("Be brief."),
+			message.New().WithRole("user").WithContent("Hello!"),
+			message.New().WithRole("assistant").WithContent("Hi!"),
+		)
+		for name, rules := range map[string][]validate.Rule{"OpenAI": validate.OpenAI, "Anthropic": validate.Anthropic, "Gemini": validate.Gemini} {
+			if err := validate.New(rules...).Validate(c); err != nil {
+				t.Errorf("expected %s to accept the conversation, got %v", name, err)
+			}
+		}
+	})
+	t.Run("returns all violations", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("assistant").WithContent("Hi!"),
+			message.New().WithRole("assistant").WithContent(" "),
+			message.New().WithRole("system").WithContent("Be brief."),
+			message.New().WithRole("critic").WithContent("Too long."),
+		)
+		err := validate.New(validate.Gemini...).WithRules(validate.SystemFirst, validate.StartsWithUser).Validate(c)
+		var violations validate.Violations
+		if !errors.As(err, &violations) {
+			t.Fatalf("expected Violations, got %v", err)
+		}
+		want := validate.Violations{
+			{Index: 1, Rule: "non-empty-content", Description: "content is empty"},
+			{Index: 3, Rule: "known-roles", Description: `unknown role "critic"`},
+			{Index: 1, Rule: "alternating", Description: "consecutive assistant messages"},
+			{Index: 2, Rule: "system-first", Description: "system message after other messages"},
+			{Index: 0, Rule: "starts-with-user", Description: "first message is not from the user"},
+		}
+		if !reflect.DeepEqual(violations, want) {
+			t.Errorf("expected violations\n%v\ngot\n%v", want, violations)
+		}
+	})
+	t.Run("gemini moves system messages", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("user").WithContent("Hello!"),
+			message.New().WithRole("system").WithContent("Be brief."),
+			message.New().WithRole("assistant").WithContent("Hi!"),
+		)
+		if err := validate.New(validate.Gemini...).Validate(c); err != nil {
+			t.Errorf("expected a system message after others to be accepted, got %v", err)
+		}
+	})
+	t.Run("anthropic merges adjacent messages", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("user").WithContent("Hello!"),
+			message.New().WithRole("user").WithContent("Are you there?"),
+			message.New().WithRole("assistant").WithContent("Hi!"),
+		)
+		if err := validate.New(validate.Anthropic...).Validate(c); err != nil {
+			t.Errorf("expected consecutive user messages to be accepted, got %v", err)
+		}
+	})
+	t.Run("tool results", func(t *testing.T) {
+		c := conversation.New().WithMessages(
+			message.New().WithRole("tool").WithContent("orphan"),
+			message.New().WithRole("user").WithContent("What's the weather?"),
+			message.New().WithRole("assistant").WithContent("get_weather()").WithMetadata(validate.MetadataToolCallID, "call_1"),
+			message.New().WithRole("tool").WithContent("sunny").WithMetadata(validate.MetadataToolCallID, "call_1"),
+			message.New().WithRole("tool").WithContent("rainy").WithMetadata(validate.MetadataToolCallID, "call_2"),
+		)
+		err := validate.New(validate.OpenAI...).Validate(c)
+		var violations validate.Violations
+		if !errors.As(err, &violations) || len(violations) != 2 {
+			t.Fatalf("expected 2 violations, got %v", err)
+		}
+		if violations[0].Index != 0 || violations[1].Index != 4 {
+			t.Errorf("unexpected violations %v", violations)
+		}
+	})
+	t.Run("registered roles", func(t *testing.T) {
+		registry := message.NewRegistry()
+		registry.Register("critic", nil)
+		c := conversation.New().WithMessages(
+			message.New().WithRole("critic").WithContent("Too long."),
+			message.New().WithRole("narrator").WithContent("Meanwhile..."),
+		)
+		err := validate.New(validate.RegisteredRoles(registry)).Validate(c)
+		var violations validate.Violations
+		if !errors.As(err, &violations) || len(violations) != 1 || violations[0].Index != 1 {
+			t.Errorf("expected the narrator message to be rejected, got %v", err)
+		}
+	})
+	t.Run("error message", func(t *testing.T) {
+		err := validate.Violations{{Index: -1, Rule: "starts-with-user", Description: "conversation has no user messages"}}
+		if err.Error() != "invalid conversation: conversation has no user messages (starts-with-user)" {
+			t.Errorf("unexpected error message %q", err.Error())
+		}
+	})
+}